@@ -0,0 +1,62 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// CachePath is the path of the file which records uploaded assets.
+var CachePath = filepath.Join(".qiitactl", "assets.json")
+
+// Cache maps the content hash of an asset to its uploaded URL.
+type Cache map[string]string
+
+// LoadCache loads the cache from CachePath.
+// An empty cache is returned when the file doesn't exist.
+func LoadCache() (cache Cache, err error) {
+	cache = make(Cache)
+	b, err := ioutil.ReadFile(CachePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	err = json.Unmarshal(b, &cache)
+	return
+}
+
+// Save writes the cache to CachePath.
+func (cache Cache) Save() (err error) {
+	err = os.MkdirAll(filepath.Dir(CachePath), 0755)
+	if err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(CachePath, b, 0644)
+	return
+}
+
+// Hash returns the hex encoded SHA-256 of the content of the file.
+func Hash(path string) (hash string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return
+	}
+	hash = hex.EncodeToString(h.Sum(nil))
+	return
+}
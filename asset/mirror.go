@@ -0,0 +1,109 @@
+package asset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var rHTTP = regexp.MustCompile(`^(?i)https?://`)
+
+// DirMirror is the name of the directory where images are downloaded next to a post.
+const DirMirror = "assets"
+
+// Mirror downloads remote images referenced in a post into a local directory.
+type Mirror struct {
+	Dir    string
+	Client *http.Client
+}
+
+// Download downloads the file at u into Dir with a name made from the hash of u,
+// and returns the path of the downloaded file.
+// Each URL has its own file, which is overwritten when it is downloaded again.
+func (m Mirror) Download(u string) (p string, err error) {
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		err = fmt.Errorf("download %s: %s", u, resp.Status)
+		return
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(u))
+	p = filepath.Join(m.Dir, hex.EncodeToString(sum[:])+extension(u, resp.Header.Get("Content-Type")))
+	err = os.MkdirAll(m.Dir, 0755)
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(p, b, 0644)
+	return
+}
+
+func extension(u, contentType string) (ext string) {
+	if parsed, err := url.Parse(u); err == nil {
+		ext = strings.ToLower(path.Ext(parsed.Path))
+	}
+	if ext != "" {
+		return
+	}
+	exts, err := mime.ExtensionsByType(contentType)
+	if err == nil && len(exts) > 0 {
+		ext = exts[0]
+	}
+	return
+}
+
+// Process downloads every image referenced with an http or https URL in body.
+// The returned body refers to the downloaded files relatively to the parent of Dir,
+// and links maps those local links to the original URLs.
+// The other links like local paths, data URIs and fragments are left as they are.
+func (m Mirror) Process(body string) (out string, links map[string]string, err error) {
+	links = make(map[string]string)
+	out, err = RewriteImages(body, func(link string) (string, error) {
+		if !rHTTP.MatchString(link) {
+			return link, nil
+		}
+		p, err := m.Download(link)
+		if err != nil {
+			return link, err
+		}
+		local := filepath.ToSlash(filepath.Join(filepath.Base(m.Dir), filepath.Base(p)))
+		links[local] = link
+		return local, nil
+	})
+	return
+}
+
+// RestoreLinks replaces the local links of mirrored images in body with their original URLs.
+func RestoreLinks(body string, links map[string]string) (out string) {
+	if len(links) == 0 {
+		out = body
+		return
+	}
+	out, _ = RewriteImages(body, func(link string) (string, error) {
+		if u, ok := links[link]; ok {
+			return u, nil
+		}
+		return link, nil
+	})
+	return
+}
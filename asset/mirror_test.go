@@ -0,0 +1,94 @@
+package asset_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minodisk/qiitactl/asset"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestMirrorProcess(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+	})
+	mux.HandleFunc("/copy-of-a.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write([]byte("b"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	m := asset.Mirror{
+		Dir: "mine/assets",
+	}
+	body := `![a](` + server.URL + `/a.png)
+![copy of a](` + server.URL + `/copy-of-a.png)
+<img src="` + server.URL + `/b">
+![local](./images/c.png)
+![protocol relative](//example.com/d.png)
+![data](data:image/png;base64,iVBORw0KGgo=)
+![fragment](#e)`
+	actual, links, err := m.Process(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := func(u string) string {
+		sum := sha256.Sum256([]byte(u))
+		return hex.EncodeToString(sum[:])
+	}
+	hashA := hash(server.URL + "/a.png")
+	hashCopyOfA := hash(server.URL + "/copy-of-a.png")
+	hashB := hash(server.URL + "/b")
+	expected := `![a](assets/` + hashA + `.png)
+![copy of a](assets/` + hashCopyOfA + `.png)
+<img src="assets/` + hashB + `.gif">
+![local](./images/c.png)
+![protocol relative](//example.com/d.png)
+![data](data:image/png;base64,iVBORw0KGgo=)
+![fragment](#e)`
+	if actual != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, actual))
+	}
+	testutil.ShouldExistFile(t, 0)
+	files, err := ioutil.ReadDir("mine/assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("each URL should be downloaded into its own file: %d files", len(files))
+	}
+
+	restored := asset.RestoreLinks(actual, links)
+	if restored != body {
+		t.Errorf("wrong restored body:\n%s", testutil.Diff(body, restored))
+	}
+}
+
+func TestMirrorDownloadWithNotFound(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	server := httptest.NewServer(http.NewServeMux())
+	defer server.Close()
+
+	m := asset.Mirror{
+		Dir: "mine/assets",
+	}
+	_, err := m.Download(server.URL + "/none.png")
+	if err == nil {
+		t.Fatal("error should occur")
+	}
+}
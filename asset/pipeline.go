@@ -0,0 +1,104 @@
+package asset
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	rMarkdownImage = regexp.MustCompile(`(!\[[^\]]*\]\()([^)\s]+)((?:\s+"[^"]*")?\))`)
+	rHTMLImage     = regexp.MustCompile(`(<img\s[^>]*?src=")([^"]+)(")`)
+	rRemote        = regexp.MustCompile(`^(?:[a-zA-Z][a-zA-Z0-9+.\-]*:|//|#)`)
+	rFence         = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// RewriteImages calls fn with the link of every image in body outside of code blocks,
+// and replaces the link with the returned one.
+func RewriteImages(body string, fn func(link string) (string, error)) (out string, err error) {
+	lines := strings.Split(body, "\n")
+	var fence string
+	for i, line := range lines {
+		if m := rFence.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[1]
+			} else if fence == m[1] {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		for _, r := range []*regexp.Regexp{rMarkdownImage, rHTMLImage} {
+			line = r.ReplaceAllStringFunc(line, func(s string) string {
+				if err != nil {
+					return s
+				}
+				m := r.FindStringSubmatch(s)
+				var link string
+				link, err = fn(m[2])
+				if err != nil {
+					return s
+				}
+				return m[1] + link + m[3]
+			})
+			if err != nil {
+				return
+			}
+		}
+		lines[i] = line
+	}
+	out = strings.Join(lines, "\n")
+	return
+}
+
+// IsLocal reports whether the link refers to a file in local.
+func IsLocal(link string) bool {
+	return !rRemote.MatchString(link)
+}
+
+// Pipeline uploads local images referenced in a body of a post.
+type Pipeline struct {
+	Uploader Uploader
+	Cache    Cache
+}
+
+// Process uploads local images referenced in body, which are resolved from dir,
+// and returns the body whose links point at the uploaded URLs.
+// Images which have been uploaded with the same content aren't uploaded again.
+func (p Pipeline) Process(body, dir string) (out string, err error) {
+	cache := p.Cache
+	if cache == nil {
+		cache = make(Cache)
+	}
+	out, err = RewriteImages(body, func(link string) (string, error) {
+		if !IsLocal(link) {
+			return link, nil
+		}
+		path, err := url.PathUnescape(link)
+		if err != nil {
+			return link, err
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		hash, err := Hash(path)
+		if err != nil {
+			return link, err
+		}
+		if u, ok := cache[hash]; ok {
+			return u, nil
+		}
+		u, err := p.Uploader.Upload(path)
+		if err != nil {
+			return link, err
+		}
+		cache[hash] = u
+		return u, nil
+	})
+	return
+}
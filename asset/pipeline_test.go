@@ -0,0 +1,88 @@
+package asset_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minodisk/qiitactl/asset"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+type countingUploader struct {
+	count int
+}
+
+func (u *countingUploader) Upload(path string) (url string, err error) {
+	u.count++
+	url = fmt.Sprintf("https://example.com/%d%s", u.count, filepath.Ext(path))
+	return
+}
+
+func TestPipelineProcess(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll("mine/images", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/images/a.png", []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/images/copy of a.png", []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/images/b.png", []byte("b"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &countingUploader{}
+	p := asset.Pipeline{
+		Uploader: u,
+		Cache:    make(asset.Cache),
+	}
+	actual, err := p.Process(`![diagram](./images/a.png)
+![same content](images/copy%20of%20a.png "title")
+<img alt="b" src="images/b.png">
+![remote](https://qiita-image-store.s3.amazonaws.com/0/1/c.png)
+
+`+"```"+`
+![in code](./images/a.png)
+`+"```", "mine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `![diagram](https://example.com/1.png)
+![same content](https://example.com/1.png "title")
+<img alt="b" src="https://example.com/2.png">
+![remote](https://qiita-image-store.s3.amazonaws.com/0/1/c.png)
+
+` + "```" + `
+![in code](./images/a.png)
+` + "```"
+	if actual != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, actual))
+	}
+	if u.count != 2 {
+		t.Errorf("images should be uploaded once per content: %d", u.count)
+	}
+}
+
+func TestPipelineProcessWithNoFile(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	p := asset.Pipeline{
+		Uploader: &countingUploader{},
+	}
+	_, err := p.Process("![](./images/none.png)", "mine")
+	if err == nil {
+		t.Fatal("error should occur")
+	}
+}
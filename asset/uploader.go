@@ -0,0 +1,89 @@
+package asset
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Uploader uploads a local file and returns the URL where the file is published.
+type Uploader interface {
+	Upload(path string) (url string, err error)
+}
+
+// CommandUploader uploads files with an external command.
+// The path of the file is appended to Command as the last argument,
+// and the command should print the uploaded URL to stdout.
+type CommandUploader struct {
+	Command string
+}
+
+// Upload runs the command and returns the URL printed by it.
+func (u CommandUploader) Upload(path string) (url string, err error) {
+	args := strings.Fields(u.Command)
+	if len(args) == 0 {
+		err = fmt.Errorf("upload: empty command")
+		return
+	}
+	args = append(args, path)
+
+	stdout := bytes.NewBuffer([]byte{})
+	stderr := bytes.NewBuffer([]byte{})
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if err != nil {
+		err = fmt.Errorf("upload %s: %s: %s", path, err, strings.TrimSpace(stderr.String()))
+		return
+	}
+
+	url = strings.TrimSpace(stdout.String())
+	if url == "" {
+		err = fmt.Errorf("upload %s: command printed no URL", path)
+		return
+	}
+	return
+}
+
+// DirUploader copies files into Dir, which is expected to be published at BaseURL.
+// It is a stand-in for a static host.
+type DirUploader struct {
+	Dir     string
+	BaseURL string
+}
+
+// Upload copies the file into the directory with a name made from its content hash.
+func (u DirUploader) Upload(path string) (url string, err error) {
+	hash, err := Hash(path)
+	if err != nil {
+		return
+	}
+	name := hash + strings.ToLower(filepath.Ext(path))
+
+	err = os.MkdirAll(u.Dir, 0755)
+	if err != nil {
+		return
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	dst, err := os.Create(filepath.Join(u.Dir, name))
+	if err != nil {
+		return
+	}
+	defer dst.Close()
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		return
+	}
+
+	url = fmt.Sprintf("%s/%s", strings.TrimRight(u.BaseURL, "/"), name)
+	return
+}
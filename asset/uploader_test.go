@@ -0,0 +1,67 @@
+package asset_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/minodisk/qiitactl/asset"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestCommandUploader(t *testing.T) {
+	u := asset.CommandUploader{
+		Command: "printf https://example.com/%s",
+	}
+	url, err := u.Upload("images/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://example.com/images/a.png" {
+		t.Errorf("wrong URL: %s", url)
+	}
+}
+
+func TestCommandUploaderWithFailure(t *testing.T) {
+	u := asset.CommandUploader{
+		Command: "false",
+	}
+	_, err := u.Upload("images/a.png")
+	if err == nil {
+		t.Fatal("error should occur")
+	}
+}
+
+func TestDirUploader(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll("mine", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/a.PNG", []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := asset.DirUploader{
+		Dir:     "foo/public",
+		BaseURL: "https://static.example.com/",
+	}
+	url, err := u.Upload("mine/a.PNG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	if url != "https://static.example.com/"+hash+".png" {
+		t.Errorf("wrong URL: %s", url)
+	}
+	b, err := ioutil.ReadFile("foo/public/" + hash + ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a" {
+		t.Errorf("wrong content: %s", b)
+	}
+}
@@ -0,0 +1,101 @@
+package command
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/minodisk/qiitactl/asset"
+	"github.com/minodisk/qiitactl/model"
+)
+
+// AssetOptions configures how local images referenced in a post are uploaded.
+type AssetOptions struct {
+	UploadCommand *string
+	UploadDir     *string
+	UploadURL     *string
+}
+
+func newAssetOptions(cmd *kingpin.CmdClause) AssetOptions {
+	return AssetOptions{
+		UploadCommand: cmd.Flag("upload-command", "The command to upload local images. It is called with the path of an image and should print the uploaded URL.").Envar("QIITACTL_UPLOAD_COMMAND").String(),
+		UploadDir:     cmd.Flag("upload-dir", "The directory to copy local images into instead of uploading them.").Envar("QIITACTL_UPLOAD_DIR").String(),
+		UploadURL:     cmd.Flag("upload-url", "The URL where the directory specified with --upload-dir is published.").Envar("QIITACTL_UPLOAD_URL").String(),
+	}
+}
+
+func (o AssetOptions) uploader() (u asset.Uploader) {
+	switch {
+	case o.UploadCommand != nil && *o.UploadCommand != "":
+		u = asset.CommandUploader{
+			Command: *o.UploadCommand,
+		}
+	case o.UploadDir != nil && *o.UploadDir != "":
+		u = asset.DirUploader{
+			Dir:     *o.UploadDir,
+			BaseURL: *o.UploadURL,
+		}
+	}
+	return
+}
+
+// assetsMutex serializes processAssets, since the cache of uploads is shared with a file.
+var assetsMutex sync.Mutex
+
+// processAssets preprocesses the body of the post with SentBody,
+// uploads local images referenced in the post
+// and returns the body to be sent to Qiita.
+// The body of the post itself isn't changed.
+func (o AssetOptions) processAssets(post model.Post) (body string, err error) {
+	body, err = post.SentBody()
+	if err != nil {
+		return
+	}
+	u := o.uploader()
+	if u == nil {
+		return
+	}
+	assetsMutex.Lock()
+	defer assetsMutex.Unlock()
+
+	cache, err := asset.LoadCache()
+	if err != nil {
+		return
+	}
+	p := asset.Pipeline{
+		Uploader: u,
+		Cache:    cache,
+	}
+	body, err = p.Process(body, filepath.Dir(post.Path))
+	if err != nil {
+		return
+	}
+	err = cache.Save()
+	return
+}
+
+// mirrorAssets downloads images in the post into the assets directory next to the file.
+// When localLinks is true, the links in the file are rewritten to the downloaded files
+// and the original URLs are recorded in the meta to be restored when sending.
+// The links recorded when the post was fetched before are kept.
+func mirrorAssets(post *model.Post, localLinks bool) (err error) {
+	m := asset.Mirror{
+		Dir: filepath.Join(filepath.Dir(post.Path), asset.DirMirror),
+	}
+	body, links, err := m.Process(post.Body)
+	if err != nil {
+		return
+	}
+	if !localLinks {
+		return
+	}
+	post.Body = body
+	if post.Assets == nil {
+		post.Assets = make(map[string]string)
+	}
+	for local, u := range links {
+		post.Assets[local] = u
+	}
+	err = post.Save(map[string]string{post.ID: post.Path})
+	return
+}
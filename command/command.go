@@ -3,10 +3,13 @@ package command
 import (
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/minodisk/qiitactl/api"
 	"github.com/minodisk/qiitactl/info"
+	"github.com/minodisk/qiitactl/lint"
+	"github.com/minodisk/qiitactl/model"
 )
 
 type Command struct {
@@ -14,35 +17,82 @@ type Command struct {
 	Out    io.Writer
 	Error  io.Writer
 
-	Application  *kingpin.Application
-	Generate     *kingpin.CmdClause
-	GenerateFile *kingpin.CmdClause
-	Create       *kingpin.CmdClause
-	CreatePost   *kingpin.CmdClause
-	Show         *kingpin.CmdClause
-	ShowPost     *kingpin.CmdClause
-	ShowPosts    *kingpin.CmdClause
-	Fetch        *kingpin.CmdClause
-	FetchPost    *kingpin.CmdClause
-	FetchPosts   *kingpin.CmdClause
-	Update       *kingpin.CmdClause
-	UpdatePost   *kingpin.CmdClause
-	Delete       *kingpin.CmdClause
-	DeletePost   *kingpin.CmdClause
-
-	GlobalOptions      GlobalOptions
-	GenerateFileRunner GenerateFileRunner
-	CreatePostRunner   CreatePostRunner
-	ShowPostRunner     ShowPostRunner
-	ShowPostsRunner    ShowPostsRunner
-	FetchPostRunner    FetchPostRunner
-	FetchPostsRunner   FetchPostsRunner
-	UpdatePostRunner   UpdatePostRunner
-	DeletePostRunner   DeletePostRunner
+	Application           *kingpin.Application
+	Generate              *kingpin.CmdClause
+	GenerateFile          *kingpin.CmdClause
+	GenerateTemplates     *kingpin.CmdClause
+	GenerateTemplatesList *kingpin.CmdClause
+	Create                *kingpin.CmdClause
+	CreatePost            *kingpin.CmdClause
+	Show                  *kingpin.CmdClause
+	ShowPost              *kingpin.CmdClause
+	ShowPosts             *kingpin.CmdClause
+	ShowDrafts            *kingpin.CmdClause
+	Fetch                 *kingpin.CmdClause
+	FetchPost             *kingpin.CmdClause
+	FetchPosts            *kingpin.CmdClause
+	Update                *kingpin.CmdClause
+	UpdatePost            *kingpin.CmdClause
+	Delete                *kingpin.CmdClause
+	DeletePost            *kingpin.CmdClause
+	Convert               *kingpin.CmdClause
+	Rename                *kingpin.CmdClause
+	Reindex               *kingpin.CmdClause
+	Resolve               *kingpin.CmdClause
+	Status                *kingpin.CmdClause
+	Diff                  *kingpin.CmdClause
+	Sync                  *kingpin.CmdClause
+	Push                  *kingpin.CmdClause
+	Restore               *kingpin.CmdClause
+	ShowTrash             *kingpin.CmdClause
+	Purge                 *kingpin.CmdClause
+	Watch                 *kingpin.CmdClause
+	Render                *kingpin.CmdClause
+	Preview               *kingpin.CmdClause
+	Lint                  *kingpin.CmdClause
+	LSP                   *kingpin.CmdClause
+	Exec                  *kingpin.CmdClause
+	Extract               *kingpin.CmdClause
+	Publish               *kingpin.CmdClause
+	Schedule              *kingpin.CmdClause
+
+	GlobalOptions               GlobalOptions
+	GenerateFileRunner          GenerateFileRunner
+	GenerateTemplatesListRunner GenerateTemplatesListRunner
+	CreatePostRunner            CreatePostRunner
+	ShowPostRunner              ShowPostRunner
+	ShowPostsRunner             ShowPostsRunner
+	ShowDraftsRunner            ShowDraftsRunner
+	FetchPostRunner             FetchPostRunner
+	FetchPostsRunner            FetchPostsRunner
+	UpdatePostRunner            UpdatePostRunner
+	DeletePostRunner            DeletePostRunner
+	ConvertRunner               ConvertRunner
+	RenameRunner                RenameRunner
+	ReindexRunner               ReindexRunner
+	ResolveRunner               ResolveRunner
+	StatusRunner                StatusRunner
+	DiffRunner                  DiffRunner
+	SyncRunner                  SyncRunner
+	PushRunner                  PushRunner
+	RestoreRunner               RestoreRunner
+	ShowTrashRunner             ShowTrashRunner
+	PurgeRunner                 PurgeRunner
+	WatchRunner                 WatchRunner
+	RenderRunner                RenderRunner
+	PreviewRunner               PreviewRunner
+	LintRunner                  LintRunner
+	LSPRunner                   LSPRunner
+	ExecRunner                  ExecRunner
+	ExtractRunner               ExtractRunner
+	PublishRunner               PublishRunner
+	ScheduleRunner              ScheduleRunner
 }
 
 type GlobalOptions struct {
 	Debug *bool
+	// Error is the writer where warnings are outputted.
+	Error io.Writer
 }
 
 func New(info info.Info, client api.Client, out io.Writer, err io.Writer) (c Command) {
@@ -55,21 +105,28 @@ func New(info info.Info, client api.Client, out io.Writer, err io.Writer) (c Com
 	c.Application.Author(info.Author)
 	c.GlobalOptions = GlobalOptions{
 		Debug: c.Application.Flag("debug", "Enable debug mode.").Bool(),
+		Error: err,
 	}
 
 	c.Generate = c.Application.Command("generate", "Generate something in your local.")
 	c.GenerateFile = c.Generate.Command("file", "Generate a new markdown file for a new post.")
 	c.GenerateFileRunner = GenerateFileRunner{
-		Title: c.GenerateFile.Arg("title", "The title of a new post.").Required().String(),
-		Team:  c.GenerateFile.Flag("team", "The name of a team, when you post to the team.").Short('t').String(),
+		Title:    c.GenerateFile.Arg("title", "The title of a new post.").Required().String(),
+		Team:     c.GenerateFile.Flag("team", "The name of a team, when you post to the team.").Short('t').String(),
+		Template: c.GenerateFile.Flag("template", "The name of the template in .qiitactl/templates. The default template of the team by default.").String(),
+		In:       os.Stdin,
 	}
+	c.GenerateTemplates = c.Generate.Command("templates", "Manage the templates of new posts.")
+	c.GenerateTemplatesList = c.GenerateTemplates.Command("list", "List the templates of new posts.")
+	c.GenerateTemplatesListRunner = GenerateTemplatesListRunner{}
 
 	c.Create = c.Application.Command("create", "Create resources from current working directory to Qiita.")
 	c.CreatePost = c.Create.Command("post", "Create a post in Qiita.")
 	c.CreatePostRunner = CreatePostRunner{
-		File:  c.CreatePost.Arg("filename", "The filename of the post to be created").Required().File(),
-		Tweet: c.CreatePost.Flag("tweet", "Tweet the created post in Twitter.").Short('t').Bool(),
-		Gist:  c.CreatePost.Flag("gist", "Post codes in the created post to GitHub Gist.").Short('g').Bool(),
+		File:         c.CreatePost.Arg("filename", "The filename of the post to be created").Required().File(),
+		Tweet:        c.CreatePost.Flag("tweet", "Tweet the created post in Twitter.").Short('t').Bool(),
+		Gist:         c.CreatePost.Flag("gist", "Post codes in the created post to GitHub Gist.").Short('g').Bool(),
+		AssetOptions: newAssetOptions(c.CreatePost),
 	}
 
 	c.Show = c.Application.Command("show", "Display resources.")
@@ -80,6 +137,11 @@ func New(info info.Info, client api.Client, out io.Writer, err io.Writer) (c Com
 	}
 	c.ShowPosts = c.Show.Command("posts", "Display posts in Qiita.")
 	c.ShowPostsRunner = ShowPostsRunner{}
+	c.ShowDrafts = c.Show.Command("drafts", "Display drafts in local which aren't published yet.")
+	c.ShowDraftsRunner = ShowDraftsRunner{}
+
+	c.ShowTrash = c.Show.Command("trash", "Display deleted posts in the trash.")
+	c.ShowTrashRunner = ShowTrashRunner{}
 
 	c.Fetch = c.Application.Command("fetch", "Download resources from Qiita to current working directory.")
 	c.FetchPost = c.Fetch.Command("post", "Download a post as a file.")
@@ -88,38 +150,170 @@ func New(info info.Info, client api.Client, out io.Writer, err io.Writer) (c Com
 		File: c.FetchPost.Flag("filename", "The filename of the post to be created.").Short('f').File(),
 	}
 	c.FetchPosts = c.Fetch.Command("posts", "Download posts as files.")
-	c.FetchPostsRunner = FetchPostsRunner{}
+	c.FetchPostsRunner = FetchPostsRunner{
+		WithAssets: c.FetchPosts.Flag("with-assets", "Download images in the posts into assets directory next to the files.").Bool(),
+		LocalLinks: c.FetchPosts.Flag("local-links", "Rewrite links of the downloaded images to the local files. It requires --with-assets.").Bool(),
+	}
 
 	c.Update = c.Application.Command("update", "Update resources from current working directory to Qiita.")
 	c.UpdatePost = c.Update.Command("post", "Update a post in Qiita.")
 	c.UpdatePostRunner = UpdatePostRunner{
-		File: c.UpdatePost.Arg("filename", "The filename of the post to be updated.").Required().File(),
+		File:         c.UpdatePost.Arg("filename", "The filename of the post to be updated.").Required().File(),
+		Force:        c.UpdatePost.Flag("force", "Overwrite the post even if it is updated in Qiita after it is fetched.").Short('f').Bool(),
+		Rebase:       c.UpdatePost.Flag("rebase", "Merge the changes in Qiita into the file before updating.").Bool(),
+		AssetOptions: newAssetOptions(c.UpdatePost),
 	}
 
 	c.Delete = c.Application.Command("delete", "Delete resources from current working directory to Qiita.")
 	c.DeletePost = c.Delete.Command("post", "Delete a post in Qiita.")
 	c.DeletePostRunner = DeletePostRunner{
-		File: c.DeletePost.Arg("filename", "The filename of the post to be deleted.").Required().File(),
+		File: c.DeletePost.Arg("filename", "The filename of the post to be deleted.").Required().ExistingFile(),
+		Yes:  c.DeletePost.Flag("yes", "Delete without confirmation.").Short('y').Bool(),
+		In:   os.Stdin,
+	}
+
+	c.Publish = c.Application.Command("publish", "Publish a draft in local as a new post in Qiita.")
+	c.PublishRunner = PublishRunner{
+		File:         c.Publish.Arg("filename", "The filename of the draft to be published.").File(),
+		Due:          c.Publish.Flag("due", "Publish all drafts whose publish_at has passed.").Bool(),
+		Tweet:        c.Publish.Flag("tweet", "Tweet the published post in Twitter.").Short('t').Bool(),
+		Gist:         c.Publish.Flag("gist", "Post codes in the published post to GitHub Gist.").Short('g').Bool(),
+		AssetOptions: newAssetOptions(c.Publish),
+	}
+	c.Schedule = c.Application.Command("schedule", "Keep running and publish drafts when their publish_at comes.")
+	c.ScheduleRunner = ScheduleRunner{
+		Interval:     c.Schedule.Flag("interval", "The maximum interval to look for new drafts.").Default("10m").Duration(),
+		Tweet:        c.Schedule.Flag("tweet", "Tweet the published posts in Twitter.").Short('t').Bool(),
+		Gist:         c.Schedule.Flag("gist", "Post codes in the published posts to GitHub Gist.").Short('g').Bool(),
+		AssetOptions: newAssetOptions(c.Schedule),
+	}
+
+	c.Convert = c.Application.Command("convert", "Convert the format of markdown files in current working directory.")
+	c.ConvertRunner = ConvertRunner{
+		Paths: c.Convert.Arg("paths", "The files or directories to be converted.").Strings(),
+		To:    c.Convert.Flag("to", "The format to convert into. The format of the workspace is used by default.").Enum(string(model.FormatComment), string(model.FormatFrontMatter)),
+	}
+
+	c.Rename = c.Application.Command("rename", "Move files of posts to the paths made with path_template of the workspace.")
+	c.RenameRunner = RenameRunner{
+		DryRun: c.Rename.Flag("dry-run", "Report the moves without moving files.").Short('n').Bool(),
+	}
+
+	c.Reindex = c.Application.Command("reindex", "Rebuild the index of local files of posts.")
+	c.ReindexRunner = ReindexRunner{}
+
+	c.Resolve = c.Application.Command("resolve", "Mark conflicts left by fetch or update --rebase as resolved.")
+	c.ResolveRunner = ResolveRunner{
+		Paths: c.Resolve.Arg("filenames", "The files whose conflicts are resolved. All the files with conflicts by default.").Strings(),
+	}
+
+	c.Status = c.Application.Command("status", "Show the files of posts which differ from Qiita.")
+	c.StatusRunner = StatusRunner{
+		JSON: c.Status.Flag("json", "Output as JSON.").Bool(),
+	}
+
+	c.Diff = c.Application.Command("diff", "Show the changes which will be sent to Qiita by updating a post.")
+	c.DiffRunner = DiffRunner{
+		File:            c.Diff.Arg("filename", "The filename of the post.").Required().ExistingFile(),
+		Stat:            c.Diff.Flag("stat", "Show only the numbers of changed lines.").Bool(),
+		RemoteSinceBase: c.Diff.Flag("remote-since-base", "Show the changes in Qiita since the post was fetched.").Bool(),
+	}
+
+	c.Sync = c.Application.Command("sync", "Pull changes in Qiita, push local changes and publish due drafts.")
+	c.SyncRunner = SyncRunner{
+		DryRun:       c.Sync.Flag("dry-run", "Report what would be done without doing it.").Short('n').Bool(),
+		Teams:        c.Sync.Flag("team", "The ID of the team to be synced, or mine for the posts in Qiita. All the teams by default.").Strings(),
+		AssetOptions: newAssetOptions(c.Sync),
+	}
+
+	c.Push = c.Application.Command("push", "Update all the posts modified in local since they were fetched.")
+	c.PushRunner = PushRunner{
+		Concurrency:  c.Push.Flag("concurrency", "The number of posts updated at the same time.").Short('c').Default("4").Int(),
+		Force:        c.Push.Flag("force", "Overwrite the posts even if they are updated in Qiita after they are fetched.").Short('f').Bool(),
+		AssetOptions: newAssetOptions(c.Push),
+	}
+
+	c.Restore = c.Application.Command("restore", "Create a deleted post in the trash again as a new post.")
+	c.RestoreRunner = RestoreRunner{
+		ID:           c.Restore.Arg("id", "The ID of the deleted post.").Required().String(),
+		AssetOptions: newAssetOptions(c.Restore),
+	}
+
+	c.Purge = c.Application.Command("purge", "Remove deleted posts from the trash permanently.")
+	c.PurgeRunner = PurgeRunner{
+		IDs: c.Purge.Arg("ids", "The IDs of the deleted posts.").Strings(),
+		All: c.Purge.Flag("all", "Remove all the posts in the trash.").Bool(),
+	}
+
+	c.Watch = c.Application.Command("watch", "Keep running and update the posts in Qiita when their files are saved.")
+	c.WatchRunner = WatchRunner{
+		Paths:         c.Watch.Arg("paths", "The files or directories to be watched. Current working directory by default.").Strings(),
+		Interval:      c.Watch.Flag("interval", "The interval to poll the files.").Default("1s").Duration(),
+		Debounce:      c.Watch.Flag("debounce", "The time to wait after a file is saved before updating the post.").Default("2s").Duration(),
+		DraftsPreview: c.Watch.Flag("drafts-preview", "Push the drafts to private items to preview them.").Bool(),
+		Force:         c.Watch.Flag("force", "Overwrite the posts even if they are updated in Qiita after they are fetched.").Short('f').Bool(),
+		AssetOptions:  newAssetOptions(c.Watch),
+	}
+
+	c.Render = c.Application.Command("render", "Print the body of a post rendered into HTML without Qiita.")
+	c.RenderRunner = RenderRunner{
+		File: c.Render.Arg("filename", "The filename of the post to be rendered.").Required().File(),
+	}
+
+	c.Preview = c.Application.Command("preview", "Serve the posts rendered into HTML, which are reloaded when the files are saved.")
+	c.PreviewRunner = PreviewRunner{
+		File: c.Preview.Arg("filename", "The filename of the post to be opened first.").ExistingFile(),
+		Addr: c.Preview.Flag("addr", "The address to listen on.").Default("localhost:8000").String(),
+	}
+
+	c.Lint = c.Application.Command("lint", "Check the files of posts with the rules configured in the workspace.")
+	c.LintRunner = LintRunner{
+		Paths:  c.Lint.Arg("paths", "The files or directories to be checked. All the files of posts by default.").Strings(),
+		Format: c.Lint.Flag("format", "The format of the problems.").Default(lint.FormatText).Enum(lint.FormatText, lint.FormatJSON, lint.FormatSARIF),
+		Fix:    c.Lint.Flag("fix", "Fix the problems which can be fixed automatically.").Bool(),
+	}
+
+	c.LSP = c.Application.Command("lsp", "Serve the Language Server Protocol over stdio for the files of posts.")
+	c.LSPRunner = LSPRunner{
+		AssetOptions: newAssetOptions(c.LSP),
+		In:           os.Stdin,
+	}
+
+	c.Exec = c.Application.Command("exec", "Run the code blocks marked like go:run in a file and record their output.")
+	c.ExecRunner = ExecRunner{
+		File:    c.Exec.Arg("filename", "The filename of the post.").Required().ExistingFile(),
+		Check:   c.Exec.Flag("check", "Fail without changing the file when any recorded output is out of date.").Bool(),
+		Timeout: c.Exec.Flag("timeout", "The time limit to run each code block.").Default("10s").Duration(),
+	}
+
+	c.Extract = c.Application.Command("extract", "Write the code blocks in posts into files.")
+	c.ExtractRunner = ExtractRunner{
+		Paths: c.Extract.Arg("paths", "The files or directories of posts.").Required().Strings(),
+		Out:   c.Extract.Flag("out", "The directory where the files are written.").Short('o').Required().String(),
 	}
 
 	return
 }
 
-func (c Command) Run(args []string) {
-	var err error
-
+// Run runs the command specified with args.
+// The error is reported to the error writer and returned.
+func (c Command) Run(args []string) (err error) {
 	cmd, err := c.Application.Parse(args[1:])
 	c.Client.DebugMode(*c.GlobalOptions.Debug)
 
 	switch kingpin.MustParse(cmd, err) {
 	case c.GenerateFile.FullCommand():
 		err = c.GenerateFileRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.GenerateTemplatesList.FullCommand():
+		err = c.GenerateTemplatesListRunner.Run(c.Client, c.GlobalOptions, c.Out)
 	case c.CreatePost.FullCommand():
 		err = c.CreatePostRunner.Run(c.Client, c.GlobalOptions, c.Out)
 	case c.ShowPost.FullCommand():
 		err = c.ShowPostRunner.Run(c.Client, c.GlobalOptions, c.Out)
 	case c.ShowPosts.FullCommand():
 		err = c.ShowPostsRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.ShowDrafts.FullCommand():
+		err = c.ShowDraftsRunner.Run(c.Client, c.GlobalOptions, c.Out)
 	case c.FetchPost.FullCommand():
 		err = c.FetchPostRunner.Run(c.Client, c.GlobalOptions, c.Out)
 	case c.FetchPosts.FullCommand():
@@ -128,9 +322,50 @@ func (c Command) Run(args []string) {
 		err = c.UpdatePostRunner.Run(c.Client, c.GlobalOptions, c.Out)
 	case c.DeletePost.FullCommand():
 		err = c.DeletePostRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Publish.FullCommand():
+		err = c.PublishRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Schedule.FullCommand():
+		err = c.ScheduleRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Convert.FullCommand():
+		err = c.ConvertRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Rename.FullCommand():
+		err = c.RenameRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Reindex.FullCommand():
+		err = c.ReindexRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Resolve.FullCommand():
+		err = c.ResolveRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Status.FullCommand():
+		err = c.StatusRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Diff.FullCommand():
+		err = c.DiffRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Sync.FullCommand():
+		err = c.SyncRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Push.FullCommand():
+		err = c.PushRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Restore.FullCommand():
+		err = c.RestoreRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.ShowTrash.FullCommand():
+		err = c.ShowTrashRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Purge.FullCommand():
+		err = c.PurgeRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Watch.FullCommand():
+		err = c.WatchRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Render.FullCommand():
+		err = c.RenderRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Preview.FullCommand():
+		err = c.PreviewRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Lint.FullCommand():
+		err = c.LintRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.LSP.FullCommand():
+		err = c.LSPRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Exec.FullCommand():
+		err = c.ExecRunner.Run(c.Client, c.GlobalOptions, c.Out)
+	case c.Extract.FullCommand():
+		err = c.ExtractRunner.Run(c.Client, c.GlobalOptions, c.Out)
 	}
 
 	if err != nil {
 		fmt.Fprintf(c.Error, "%s\n", err)
 	}
+	return
 }
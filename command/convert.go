@@ -0,0 +1,80 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type ConvertRunner struct {
+	Paths *[]string
+	To    *string
+}
+
+// Convert converts the format of markdown files of posts.
+func (r ConvertRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	format := model.Format(*r.To)
+	if format == "" {
+		var config model.Config
+		config, err = model.LoadConfig()
+		if err != nil {
+			return
+		}
+		format = config.Format
+		if format == "" {
+			format = model.FormatComment
+		}
+	}
+	err = format.Validate()
+	if err != nil {
+		return
+	}
+
+	paths := *r.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	for _, path := range paths {
+		var info os.FileInfo
+		info, err = os.Stat(path)
+		if err != nil {
+			return
+		}
+		if !info.IsDir() {
+			var post model.Post
+			post, err = model.NewPostWithFile(path)
+			if err != nil {
+				return
+			}
+			err = convert(w, post, format)
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		err = model.WalkPosts(path, func(post model.Post) error {
+			return convert(w, post, format)
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func convert(w io.Writer, post model.Post, format model.Format) (err error) {
+	if post.Format == format {
+		return
+	}
+	post.Format = format
+	err = post.Save(map[string]string{})
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "%s\n", post.Path)
+	return
+}
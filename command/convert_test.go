@@ -0,0 +1,100 @@
+package command_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestConvert(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	client := api.NewClient(nil, inf)
+
+	err := os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := `<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: true
+coediting: false
+slide: true
+tags:
+- Ruby:
+  - 0.0.1
+team: null
+-->
+
+# Example Title
+
+## Example body`
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(comment), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/README.md", []byte("# Not a post"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "convert", "--to", "front_matter"})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	if buf.String() != "mine/2000/01/01/Example Title.md\n" {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+
+	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := string(b)
+	expected := `---
+title: Example Title
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: true
+coediting: false
+slide: true
+tags:
+- Ruby:
+  - 0.0.1
+team: null
+---
+
+## Example body`
+	if actual != expected {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+
+	app.Run([]string{"qiitactl", "convert", "mine/2000/01/01/Example Title.md", "--to", "comment"})
+	e = errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	b, err = ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = string(b)
+	if actual != comment {
+		t.Errorf("wrong content:\n%s", testutil.Diff(comment, actual))
+	}
+}
@@ -0,0 +1,100 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type DiffRunner struct {
+	File            *string
+	Stat            *bool
+	RemoteSinceBase *bool
+}
+
+// Diff shows the changes which will be sent to Qiita by updating the post with the file.
+// With --remote-since-base, it shows the changes in Qiita since the post was fetched.
+func (r DiffRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	post, err := model.NewPostWithFile(*r.File)
+	if err != nil {
+		return
+	}
+	if post.Draft {
+		err = model.DraftError{
+			Path: post.Path,
+		}
+		return
+	}
+	if post.ID == "" {
+		err = model.EmptyIDError{}
+		return
+	}
+	remote, err := model.FetchPost(c, post.Team, post.ID)
+	if err != nil {
+		return
+	}
+
+	var src, dst model.Post
+	var srcName, dstName string
+	if *r.RemoteSinceBase {
+		var base *model.Post
+		base, err = model.LoadBase(post.ID)
+		if err != nil {
+			return
+		}
+		if base == nil {
+			err = fmt.Errorf("%s has no snapshot: fetch it first", post.Path)
+			return
+		}
+		src, srcName = *base, "base"
+		dst, dstName = remote, "remote"
+	} else {
+		post.Body, err = post.SentBody()
+		if err != nil {
+			return
+		}
+		src, srcName = remote, "remote"
+		dst, dstName = post, post.Path
+	}
+
+	if *r.Stat {
+		insertions, deletions := model.DiffStat(src.DiffText(), dst.DiffText())
+		_, err = fmt.Fprintf(
+			w,
+			"%s | %d %s%s\n%d insertions(+), %d deletions(-)\n",
+			post.Path,
+			insertions+deletions,
+			color.GreenString(strings.Repeat("+", insertions)),
+			color.RedString(strings.Repeat("-", deletions)),
+			insertions,
+			deletions,
+		)
+		return
+	}
+
+	diff := model.UnifiedDiff(srcName, dstName, src.DiffText(), dst.DiffText())
+	if diff == "" {
+		return
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			line = color.New(color.Bold).Sprint(line)
+		case strings.HasPrefix(line, "@@"):
+			line = color.CyanString(line)
+		case strings.HasPrefix(line, "+"):
+			line = color.GreenString(line)
+		case strings.HasPrefix(line, "-"):
+			line = color.RedString(line)
+		}
+		_, err = fmt.Fprintln(w, line)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,123 @@
+package command_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestDiff(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItem(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	err = os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: true
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+- Go
+team: null
+-->
+
+# Example Edited Title
+
+## Example body`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		buf := bytes.NewBuffer([]byte{})
+		errBuf := bytes.NewBuffer([]byte{})
+		app := command.New(inf, client, buf, errBuf)
+		app.Run(append([]string{"qiitactl", "diff"}, args...))
+		if errBuf.Len() != 0 {
+			t.Fatal(errBuf.String())
+		}
+		return buf.String()
+	}
+
+	actual := run("mine/2000/01/01/Example Title.md")
+	expected := `--- remote
++++ mine/2000/01/01/Example Title.md
+@@ -1,7 +1,8 @@
+-title: Example Title
+-private: false
++title: Example Edited Title
++private: true
+ coediting: false
+ tags:
+ - Ruby 0.0.1
++- Go
+ 
+ ## Example body
+`
+	if actual != expected {
+		t.Errorf("wrong diff:\n%s", testutil.Diff(expected, actual))
+	}
+
+	actual = run("--stat", "mine/2000/01/01/Example Title.md")
+	expected = `mine/2000/01/01/Example Title.md | 5 +++--
+3 insertions(+), 2 deletions(-)
+`
+	if actual != expected {
+		t.Errorf("wrong stat:\n%s", testutil.Diff(expected, actual))
+	}
+
+	err = model.Post{
+		Title: "Example Title",
+		Body:  "## Example old body",
+		Meta: model.Meta{
+			ID:   "4bd431809afb1bb99e4f",
+			Tags: model.Tags{{Name: "Ruby", Versions: []string{"0.0.1"}}},
+		},
+	}.SaveBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = run("--remote-since-base", "mine/2000/01/01/Example Title.md")
+	expected = `--- base
++++ remote
+@@ -4,4 +4,4 @@
+ tags:
+ - Ruby 0.0.1
+ 
+-## Example old body
++## Example body
+`
+	if actual != expected {
+		t.Errorf("wrong diff:\n%s", testutil.Diff(expected, actual))
+	}
+}
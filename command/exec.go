@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/lint"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/sandbox"
+)
+
+type ExecRunner struct {
+	File    *string
+	Check   *bool
+	Timeout *time.Duration
+}
+
+// Exec runs the code blocks marked like `go:run` in the file in sandboxes
+// and records their output in the `output` blocks following them.
+// With --check, the file isn't changed and an error is returned when any recorded output is out of date.
+func (r ExecRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	f, err := lint.NewFile(*r.File)
+	if err != nil {
+		return
+	}
+	post := f.Post
+
+	outputs := make(map[int]string)
+	failed, stale := 0, 0
+	for _, block := range model.CodeBlocks(post.Body) {
+		if block.Filename != model.FilenameRun {
+			continue
+		}
+		line := f.BodyLine + block.Start
+		output, e := sandbox.Run(block.Lang, block.Code, *r.Timeout)
+		if e != nil {
+			failed++
+			_, err = fmt.Fprintf(w, "failed %s:%d: %s\n%s", post.Path, line, e, output)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		recorded, ok := model.RecordedOutput(post.Body, block)
+		if ok && recorded == strings.TrimRight(output, "\n") {
+			continue
+		}
+		stale++
+		outputs[block.Start] = output
+		state := "updated"
+		if *r.Check {
+			state = "stale"
+		}
+		_, err = fmt.Fprintf(w, "%s %s:%d\n", state, post.Path, line)
+		if err != nil {
+			return
+		}
+	}
+
+	if !*r.Check && len(outputs) > 0 {
+		post.Body = model.RecordOutputs(post.Body, outputs)
+		err = post.Save(map[string]string{post.ID: post.Path})
+		if err != nil {
+			return
+		}
+	}
+	switch {
+	case failed > 0:
+		err = fmt.Errorf("exec: %d blocks failed", failed)
+	case *r.Check && stale > 0:
+		err = fmt.Errorf("exec: the output of %d blocks is out of date", stale)
+	}
+	return
+}
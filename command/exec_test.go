@@ -0,0 +1,83 @@
+package command_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestExec(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll("mine", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "<!--\ntags:\n- Shell\n-->\n\n# Tutorial\n\n```sh:run\necho hello\n```\n\n```sh\necho not run\n```"
+	err = ioutil.WriteFile("mine/tutorial.md", []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := api.NewClient(nil, inf)
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	err = app.Run([]string{"qiitactl", "exec", "--check", "mine/tutorial.md"})
+	if err == nil || err.Error() != "exec: the output of 1 blocks is out of date" {
+		t.Errorf("the missing output should be reported: %v", err)
+	}
+	if buf.String() != "stale mine/tutorial.md:8\n" {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+
+	buf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, buf, errBuf)
+	err = app.Run([]string{"qiitactl", "exec", "mine/tutorial.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "updated mine/tutorial.md:8\n" {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+	b, err := ioutil.ReadFile("mine/tutorial.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(b), "```sh:run\necho hello\n```\n\n```output\nhello\n```\n\n```sh\necho not run\n```") {
+		t.Errorf("the output should be recorded: %s", b)
+	}
+
+	buf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, buf, errBuf)
+	err = app.Run([]string{"qiitactl", "exec", "--check", "mine/tutorial.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("nothing should be reported: %s", buf.String())
+	}
+
+	err = ioutil.WriteFile("mine/tutorial.md", []byte(strings.Replace(string(b), "echo hello", "echo hello; exit 1", 1)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, buf, errBuf)
+	err = app.Run([]string{"qiitactl", "exec", "mine/tutorial.md"})
+	if err == nil || err.Error() != "exec: 1 blocks failed" {
+		t.Errorf("the failure should be reported: %v", err)
+	}
+	line := strings.Count(string(b[:strings.Index(string(b), "```sh:run")]), "\n") + 1
+	if buf.String() != fmt.Sprintf("failed mine/tutorial.md:%d: exit status 1\nhello\n", line) {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+}
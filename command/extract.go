@@ -0,0 +1,136 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/lint"
+	"github.com/minodisk/qiitactl/model"
+)
+
+// ExtractMapFilename is the name of the file in the output directory
+// which maps the extracted files to the lines in the posts.
+const ExtractMapFilename = "extract.json"
+
+type ExtractRunner struct {
+	Paths *[]string
+	Out   *string
+}
+
+// ExtractedBlock maps a file extracted from a code block to the post.
+type ExtractedBlock struct {
+	File string `json:"file"` // 書き出したファイルの出力ディレクトリからのパス
+	Path string `json:"path"` // 投稿のファイルのパス
+	Line int    `json:"line"` // コードの1行目の投稿のファイルでの行
+}
+
+// Extract writes the code blocks in the files of posts into the files in the output directory.
+// The files of a post are written into the directory with the path of the post without the extension,
+// and named after the filenames in the info strings or the generated names.
+// The extracted files are mapped to the lines in the posts in ExtractMapFilename.
+// The files which aren't posts are skipped in directories, and reported with the paths when given explicitly.
+func (r ExtractRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	paths, err := postPaths(*r.Paths)
+	if err != nil {
+		return
+	}
+	extracted := []ExtractedBlock{}
+	for _, path := range paths {
+		var f lint.File
+		f, err = lint.NewFile(path)
+		if err != nil {
+			err = fmt.Errorf("%s: %s", path, err)
+			return
+		}
+		var blocks []ExtractedBlock
+		blocks, err = r.extract(f)
+		if err != nil {
+			err = fmt.Errorf("%s: %s", path, err)
+			return
+		}
+		for _, block := range blocks {
+			_, err = fmt.Fprintf(w, "%s %s:%d\n", filepath.Join(*r.Out, block.File), block.Path, block.Line)
+			if err != nil {
+				return
+			}
+		}
+		extracted = append(extracted, blocks...)
+	}
+
+	err = os.MkdirAll(*r.Out, 0755)
+	if err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(extracted, "", "  ")
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(filepath.Join(*r.Out, ExtractMapFilename), b, 0644)
+	return
+}
+
+// extract writes the code blocks in the file except the `output` blocks.
+func (r ExtractRunner) extract(f lint.File) (extracted []ExtractedBlock, err error) {
+	dir := extractDir(f.Post.Path)
+	names := make(map[string]bool)
+	for i, block := range model.CodeBlocks(f.Post.Body) {
+		if block.Lang == model.LangOutput {
+			continue
+		}
+		name := extractName(block, i+1)
+		if names[name] {
+			ext := filepath.Ext(name)
+			name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i+1, ext)
+		}
+		names[name] = true
+
+		file := filepath.Join(dir, name)
+		err = os.MkdirAll(filepath.Join(*r.Out, filepath.Dir(file)), 0755)
+		if err != nil {
+			return
+		}
+		err = ioutil.WriteFile(filepath.Join(*r.Out, file), []byte(block.Code+"\n"), 0644)
+		if err != nil {
+			return
+		}
+		extracted = append(extracted, ExtractedBlock{
+			File: filepath.ToSlash(file),
+			Path: filepath.ToSlash(f.Post.Path),
+			Line: f.BodyLine + block.Start + 1,
+		})
+	}
+	return
+}
+
+// extractDir returns the directory for the files extracted from the post at path,
+// which is the path without the extension relative to the current directory.
+func extractDir(path string) string {
+	dir := strings.TrimSuffix(path, filepath.Ext(path))
+	if wd, err := os.Getwd(); err == nil && filepath.IsAbs(dir) {
+		if rel, err := filepath.Rel(wd, dir); err == nil {
+			dir = rel
+		}
+	}
+	dir = filepath.Clean(dir)
+	if dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		dir = filepath.Base(dir)
+	}
+	return dir
+}
+
+// extractName returns the filename in the info string of the block,
+// or the name generated with the index of the block like `block01.go`.
+func extractName(block model.CodeBlock, index int) string {
+	name := filepath.Clean(filepath.FromSlash(block.Filename))
+	if block.Filename == "" || block.Filename == model.FilenameRun || filepath.IsAbs(name) ||
+		name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return fmt.Sprintf("block%02d%s", index, block.Extension())
+	}
+	return name
+}
@@ -0,0 +1,104 @@
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestExtract(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll("mine/2024/05/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2024/05/01/tutorial.md", []byte(`<!--
+tags:
+- Go
+-->
+
+# Tutorial
+
+`+"```go:main.go\npackage main\n```"+`
+
+`+"```go:main.go\npackage main\n\nfunc main() {}\n```"+`
+
+`+"```go:run\npackage main\n```"+`
+
+`+"```output\nhello\n```"+`
+
+`+"```go:../escape.go\npackage escape\n```"+`
+
+`+"```\nplain\n```"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2024/README.md", []byte("# Posts in 2024"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := api.NewClient(nil, inf)
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	err = app.Run([]string{"qiitactl", "extract", "--out", "mine/out", "mine/2024"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != `mine/out/mine/2024/05/01/tutorial/main.go mine/2024/05/01/tutorial.md:9
+mine/out/mine/2024/05/01/tutorial/main-2.go mine/2024/05/01/tutorial.md:13
+mine/out/mine/2024/05/01/tutorial/block03.go mine/2024/05/01/tutorial.md:19
+mine/out/mine/2024/05/01/tutorial/block05.go mine/2024/05/01/tutorial.md:27
+mine/out/mine/2024/05/01/tutorial/block06.txt mine/2024/05/01/tutorial.md:31
+` {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+
+	for path, content := range map[string]string{
+		"mine/out/mine/2024/05/01/tutorial/main.go":     "package main\n",
+		"mine/out/mine/2024/05/01/tutorial/main-2.go":   "package main\n\nfunc main() {}\n",
+		"mine/out/mine/2024/05/01/tutorial/block06.txt": "plain\n",
+	} {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("wrong content of %s: %s", path, b)
+		}
+	}
+
+	b, err := ioutil.ReadFile("mine/out/extract.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var extracted []command.ExtractedBlock
+	err = json.Unmarshal(b, &extracted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extracted) != 5 || extracted[1] != (command.ExtractedBlock{
+		File: "mine/2024/05/01/tutorial/main-2.go",
+		Path: "mine/2024/05/01/tutorial.md",
+		Line: 13,
+	}) {
+		t.Errorf("wrong map: %+v", extracted)
+	}
+
+	buf = bytes.NewBuffer([]byte{})
+	errBuf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, buf, errBuf)
+	err = app.Run([]string{"qiitactl", "extract", "--out", "mine/out", "mine/2024/README.md"})
+	if err == nil || err.Error() != "mine/2024/README.md: wrong format" {
+		t.Errorf("the file which isn't a post should be reported with the path: %v", err)
+	}
+}
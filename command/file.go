@@ -1,19 +1,26 @@
 package command
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/minodisk/qiitactl/api"
 	"github.com/minodisk/qiitactl/model"
 )
 
 type GenerateFileRunner struct {
-	Title *string
-	Team  *string
+	Title    *string
+	Team     *string
+	Template *string
+	In       io.Reader
 }
 
 // GenerateFile generates markdown file at current working directory.
+// The file is generated from the template given with --template or the default template of the team,
+// and only with the title when no template is given.
 func (r GenerateFileRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
 	var team *model.Team
 	if *r.Team != "" {
@@ -22,7 +29,11 @@ func (r GenerateFileRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err
 		}
 	}
 
-	post := model.NewPost(*r.Title, nil, team)
+	post, err := r.post(c, team, w)
+	if err != nil {
+		return
+	}
+	post.Draft = true
 	err = post.Save(nil)
 	if err != nil {
 		return
@@ -32,3 +43,80 @@ func (r GenerateFileRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err
 
 	return
 }
+
+// post returns a new post generated from the template,
+// where the values prompted in the template are asked with w and read from In.
+func (r GenerateFileRunner) post(c api.Client, team *model.Team, w io.Writer) (post model.Post, err error) {
+	name := *r.Template
+	if name == "" {
+		var config model.Config
+		config, err = model.LoadConfig()
+		if err != nil {
+			return
+		}
+		id := model.DirMine
+		if team != nil {
+			id = team.ID
+		}
+		name = config.DefaultTemplates[id]
+	}
+	if name == "" {
+		post = model.NewPost(*r.Title, nil, team)
+		return
+	}
+
+	t, err := model.LoadTemplate(name)
+	if err != nil {
+		return
+	}
+	in := bufio.NewReader(r.In)
+	post, err = t.Execute(*r.Title, team, func() (id string, err error) {
+		user, err := model.FetchAuthenticatedUser(c)
+		id = user.ID
+		return
+	}, func(name string) (answer string, err error) {
+		_, err = fmt.Fprintf(w, "%s: ", name)
+		if err != nil {
+			return
+		}
+		answer, err = in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return
+		}
+		err = nil
+		answer = strings.TrimSpace(answer)
+		return
+	})
+	return
+}
+
+type GenerateTemplatesListRunner struct{}
+
+// GenerateTemplatesList lists the templates of new posts with the teams which use them by default.
+func (r GenerateTemplatesListRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	templates, err := model.LoadTemplates()
+	if err != nil {
+		return
+	}
+	config, err := model.LoadConfig()
+	if err != nil {
+		return
+	}
+	defaults := make(map[string][]string)
+	for team, name := range config.DefaultTemplates {
+		defaults[name] = append(defaults[name], team)
+	}
+
+	for _, t := range templates {
+		line := t.Name
+		if teams := defaults[t.Name]; len(teams) > 0 {
+			sort.Strings(teams)
+			line = fmt.Sprintf("%s (default: %s)", line, strings.Join(teams, ", "))
+		}
+		_, err = fmt.Fprintf(w, "%s\n", line)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
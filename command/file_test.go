@@ -1,14 +1,22 @@
 package command_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/minodisk/qiitactl/api"
 	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
 	"github.com/minodisk/qiitactl/testutil"
 )
 
@@ -89,3 +97,205 @@ func TestGenerateUniqueFile(t *testing.T) {
 		t.Errorf("file should exist at %s", path)
 	}
 }
+
+func writeTemplates(t *testing.T) {
+	files := map[string]string{
+		"article.md": `<!--
+private: true
+coediting: true
+tags:
+- Go
+- {{.Prompt "Tag" | yaml}}
+-->
+
+# {{.Title}}
+
+Written by @{{.User}} on {{.Date}} for {{if .Team}}{{.Team}}{{else}}Qiita{{end}}.
+Go {{.Prompt "Tag"}}.
+`,
+		"report.md": `---
+title: Report {{.Date}}
+tags:
+- Report
+---
+
+## Done
+`,
+	}
+	err := os.MkdirAll(model.TemplatesDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(model.TemplatesDir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ioutil.WriteFile(model.ConfigPath, []byte("default_templates:\n  increments: report\n  foo: report\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateFileWithTemplate(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+	writeTemplates(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/authenticated_user", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "yaotti"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.GenerateFileRunner.In = strings.NewReader("Testing\n")
+	err = app.Run([]string{"qiitactl", "generate", "file", "--template", "article", "Example Title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().Format("2006-01-02")
+	path := fmt.Sprintf("mine/%s/Example Title.md", time.Now().Format("2006/01/02"))
+	if buf.String() != "Tag: "+path+"\n" {
+		t.Errorf("the value should be prompted only once: %s", buf.String())
+	}
+	post, err := model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Example Title" || !post.Draft || !post.Private || !post.Coediting ||
+		len(post.Tags) != 2 || post.Tags[1].Name != "Testing" || post.Team != nil {
+		t.Errorf("wrong post: %+v", post)
+	}
+	if post.Body != fmt.Sprintf("Written by @yaotti on %s for Qiita.\nGo Testing.", date) {
+		t.Errorf("wrong body: %s", post.Body)
+	}
+}
+
+func TestGenerateFileWithTemplateQuotingValues(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll(model.TemplatesDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(model.TemplatesDir, "tips.md"), []byte(`---
+title: {{yaml .Title}}
+tags:
+- {{.Prompt "Tag" | yaml}}
+---
+
+{{.Title}}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := api.NewClient(nil, inf)
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.GenerateFileRunner.In = strings.NewReader("- 'Go' #-->\n")
+	err = app.Run([]string{"qiitactl", "generate", "file", "--template", "tips", "Go: tips"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := model.NewPostWithFile(strings.TrimSpace(strings.TrimPrefix(buf.String(), "Tag: ")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Go: tips" || len(post.Tags) != 1 || post.Tags[0].Name != "- 'Go' #-->" || post.Body != "Go: tips" {
+		t.Errorf("wrong post: %+v", post)
+	}
+}
+
+func TestGenerateFileWithTemplateFailingToFetchUser(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+	writeTemplates(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/authenticated_user", func(w http.ResponseWriter, r *http.Request) {
+		testutil.ResponseError(w, 500, errors.New("unavailable"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.GenerateFileRunner.In = strings.NewReader("Testing\n")
+	err = app.Run([]string{"qiitactl", "generate", "file", "--template", "article", "Example Title"})
+	if err == nil || !strings.HasPrefix(err.Error(), "template article: fetch the authenticated user: ") {
+		t.Errorf("the error of the API should be prefixed with the template: %v", err)
+	}
+}
+
+func TestGenerateFileWithDefaultTemplate(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+	writeTemplates(t)
+
+	client := api.NewClient(nil, inf)
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, os.Stdout, errBuf)
+	err := app.Run([]string{"qiitactl", "generate", "file", "-t", "increments", "Example Title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().Format("2006-01-02")
+	path := fmt.Sprintf("increments/%s/Report %s.md", time.Now().Format("2006/01/02"), date)
+	post, err := model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Report "+date || post.Team == nil || post.Team.ID != "increments" ||
+		len(post.Tags) != 1 || post.Tags[0].Name != "Report" || post.Body != "## Done" {
+		t.Errorf("wrong post: %+v", post)
+	}
+
+	app = command.New(inf, client, os.Stdout, errBuf)
+	err = app.Run([]string{"qiitactl", "generate", "file", "--template", "missing", "Example Title"})
+	if _, ok := err.(model.TemplateNotFoundError); !ok {
+		t.Errorf("TemplateNotFoundError should be returned: %v", err)
+	}
+}
+
+func TestGenerateTemplatesList(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+	writeTemplates(t)
+
+	client := api.NewClient(nil, inf)
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	err := app.Run([]string{"qiitactl", "generate", "templates", "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "article\nreport (default: foo, increments)\n" {
+		t.Errorf("wrong templates: %s", buf.String())
+	}
+}
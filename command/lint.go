@@ -0,0 +1,125 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/lint"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type LintRunner struct {
+	Paths  *[]string
+	Format *string
+	Fix    *bool
+}
+
+// Lint checks the files of posts with the rules configured in the workspace and reports the problems.
+// With --fix, the problems which can be fixed automatically are fixed before checking.
+func (r LintRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	config, err := model.LoadConfig()
+	if err != nil {
+		return
+	}
+	paths, err := postPaths(*r.Paths)
+	if err != nil {
+		return
+	}
+
+	diags := []lint.Diagnostic{}
+	for _, path := range paths {
+		var f lint.File
+		f, err = lint.NewFile(path)
+		if err != nil {
+			diags = append(diags, lint.Unparsable(path, err))
+			err = nil
+			continue
+		}
+		if *r.Fix {
+			f, err = fix(f, config)
+			if err != nil {
+				return
+			}
+		}
+		var ds []lint.Diagnostic
+		ds, err = lint.Lint(f, config.Lint)
+		if err != nil {
+			return
+		}
+		diags = append(diags, ds...)
+	}
+	err = lint.Write(w, *r.Format, diags)
+	if err != nil {
+		return
+	}
+
+	errors := 0
+	for _, d := range diags {
+		if d.Severity == lint.SeverityError {
+			errors++
+		}
+	}
+	if errors > 0 {
+		err = fmt.Errorf("lint: %d errors are found", errors)
+	}
+	return
+}
+
+// postPaths returns the files in args and the files of posts in the directories in args.
+// All the files of posts are returned without args.
+// The files which aren't posts, like README.md, are returned only when they are in args.
+func postPaths(args []string) (paths []string, err error) {
+	var dirs []string
+	files := make(map[string]bool)
+	for _, p := range args {
+		var info os.FileInfo
+		info, err = os.Stat(p)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			dirs = append(dirs, p)
+			continue
+		}
+		files[filepath.Clean(p)] = true
+	}
+	if len(dirs) > 0 || len(files) == 0 {
+		var index model.Index
+		index, err = model.UpdateIndex()
+		if err != nil {
+			return
+		}
+		for path, entry := range index {
+			if entry.Invalid {
+				continue
+			}
+			if inPaths(path, dirs) {
+				files[path] = true
+			}
+		}
+	}
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return
+}
+
+// fix fixes the problems in the file and saves it when it is changed.
+func fix(f lint.File, config model.Config) (fixed lint.File, err error) {
+	post, changed, err := lint.Fix(f, config.Lint)
+	if err != nil || !changed {
+		fixed = f
+		return
+	}
+	err = post.Save(map[string]string{post.ID: post.Path})
+	if err != nil {
+		return
+	}
+	fixed, err = lint.NewFile(post.Path)
+	return
+}
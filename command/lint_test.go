@@ -0,0 +1,158 @@
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/lint"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestLint(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll("mine", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/good.md", []byte("<!--\ntags:\n- Go\n-->\n\n# Good\n\nBody"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/bad.md", []byte("<!--\ntags:\n- Go\n- go\n-->\n\n# Bad\n\nSee https://example.com"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/broken.md", []byte("no header"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, api.NewClient(nil, inf), buf, errBuf)
+	err = app.Run([]string{"qiitactl", "lint", "mine"})
+	if err == nil {
+		t.Fatal("error should be returned when any error is found")
+	}
+	expected := `mine/bad.md:2:1: error: the tag "go" is duplicated (duplicate-tags)
+mine/bad.md:9:5: warning: the URL https://example.com isn't written as a link (bare-url)
+`
+	if buf.String() != expected {
+		t.Errorf("the files which aren't posts shouldn't be linted in directories:\n%s", testutil.Diff(expected, buf.String()))
+	}
+	if errBuf.String() != "lint: 1 errors are found\n" {
+		t.Errorf("wrong error: %s", errBuf.String())
+	}
+
+	buf = bytes.NewBuffer([]byte{})
+	errBuf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, api.NewClient(nil, inf), buf, errBuf)
+	app.Run([]string{"qiitactl", "lint", "mine/broken.md"})
+	if buf.String() != "mine/broken.md:1:1: error: wrong format (format)\n" {
+		t.Errorf("the file given explicitly should be reported: %s", buf.String())
+	}
+
+	buf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, api.NewClient(nil, inf), buf, errBuf)
+	app.Run([]string{"qiitactl", "lint", "--format", "json", "mine/bad.md"})
+	var diags []lint.Diagnostic
+	err = json.Unmarshal(buf.Bytes(), &diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 2 || diags[0].Rule != "duplicate-tags" || !diags[0].Fixable || diags[1].Severity != lint.SeverityWarning {
+		t.Errorf("wrong diagnostics: %s", buf.String())
+	}
+
+	buf = bytes.NewBuffer([]byte{})
+	errBuf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, api.NewClient(nil, inf), buf, errBuf)
+	err = app.Run([]string{"qiitactl", "lint", "--fix", "mine/bad.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "" {
+		t.Errorf("the problems should be fixed: %s", buf.String())
+	}
+	b, err := ioutil.ReadFile("mine/bad.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(b)
+	if !strings.Contains(content, "tags:\n- Go\n") || strings.Contains(content, "- go\n") || !strings.HasSuffix(content, "\n# Bad\n\nSee <https://example.com>") {
+		t.Errorf("wrong content:\n%s", content)
+	}
+}
+
+func TestSendingPostsWithLintErrors(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`{"id": "4bd431809afb1bb99e4f", "updated_at": "2000-01-01T00:00:00+00:00"}`))
+			return
+		}
+		t.Errorf("no post should be sent: %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = server.URL + "/api/v2" + path
+		return
+	}, inf)
+
+	err = os.MkdirAll("mine", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/new.md", []byte("<!--\ntags:\n-->\n\n# Title\n\nBody"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/published.md", []byte("<!--\nid: 4bd431809afb1bb99e4f\nupdated_at: 2000-01-01T09:00:00+09:00\ntags:\n-->\n\n# Title\n\nBody"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = model.Post{
+		Title: "Title",
+		Body:  "Old body",
+		Meta:  model.Meta{ID: "4bd431809afb1bb99e4f"},
+	}.SaveBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"qiitactl", "create", "post", "mine/new.md"}, "mine/new.md:2:1: error: 0 tags are given, but 1 to 5 tags are required (tag-count)"},
+		{[]string{"qiitactl", "publish", "mine/new.md"}, "mine/new.md:2:1: error: 0 tags are given, but 1 to 5 tags are required (tag-count)"},
+		{[]string{"qiitactl", "update", "post", "mine/published.md"}, "mine/published.md:4:1: error: 0 tags are given, but 1 to 5 tags are required (tag-count)"},
+		{[]string{"qiitactl", "push"}, "lint: the problems must be fixed before sending the post to Qiita"},
+	} {
+		buf := bytes.NewBuffer([]byte{})
+		errBuf := bytes.NewBuffer([]byte{})
+		app := command.New(inf, client, buf, errBuf)
+		app.Run(c.args)
+		if !strings.Contains(buf.String()+errBuf.String(), c.expected) {
+			t.Errorf("%s should be blocked by lint: %s%s", c.args[1], buf.String(), errBuf.String())
+		}
+	}
+}
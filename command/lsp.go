@@ -0,0 +1,30 @@
+package command
+
+import (
+	"io"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/lsp"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type LSPRunner struct {
+	AssetOptions
+	In io.Reader
+}
+
+// LSP serves the Language Server Protocol over stdio for the files of posts in current working directory.
+// The drafts are published and the posts are updated with the code actions as publish and update post do.
+func (r LSPRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	s := lsp.Server{
+		Client: c,
+		Publish: func(path string) (model.Post, error) {
+			return publishFile(c, o.Error, path, model.CreationOptions{}, r.AssetOptions)
+		},
+		Update: func(path string) (model.Post, error) {
+			return updateFile(c, o.Error, path, false, false, r.AssetOptions)
+		},
+	}
+	err = s.Serve(r.In, w)
+	return
+}
@@ -0,0 +1,124 @@
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/lsp"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestLSP(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItem(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	err = os.MkdirAll("mine", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/post.md", []byte(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+reviewers:
+- yaotti
+tags:
+- Ruby
+-->
+
+# Example Edited Title
+
+## Example Edited Body`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs, err := filepath.Abs("mine/post.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/draft.md", []byte(`<!--
+draft: true
+-->
+
+# Example Draft Title
+
+## Example Draft Body`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	draft, err := filepath.Abs("mine/draft.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := bytes.NewBuffer([]byte{})
+	for i, msg := range []map[string]interface{}{
+		{"id": 1, "method": "initialize", "params": map[string]interface{}{}},
+		{"id": 2, "method": "workspace/executeCommand", "params": map[string]interface{}{
+			"command":   lsp.CommandUpdate,
+			"arguments": []string{"file://" + filepath.ToSlash(abs)},
+		}},
+		{"id": 3, "method": "workspace/executeCommand", "params": map[string]interface{}{
+			"command":   lsp.CommandPublish,
+			"arguments": []string{"file://" + filepath.ToSlash(draft)},
+		}},
+		{"id": 4, "method": "shutdown"},
+		{"method": "exit"},
+	} {
+		msg["jsonrpc"] = "2.0"
+		b, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.LSPRunner.In = in
+	err = app.Run([]string{"qiitactl", "lsp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"message":"updated mine/post.md https://qiita.com/yaotti/items/4bd431809afb1bb99e4f"`) {
+		t.Errorf("the post should be updated: %s", buf.String())
+	}
+	if errBuf.String() != "warning: unknown key in meta isn't sent: reviewers\n" {
+		t.Errorf("unknown keys should be warned in the error writer: %q", errBuf.String())
+	}
+	if strings.Contains(buf.String(), "warning:") {
+		t.Errorf("warnings shouldn't be written into the protocol: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "tag-count") {
+		t.Errorf("the draft without tags should be blocked by lint: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `{"jsonrpc":"2.0","id":4,"result":null}`) {
+		t.Errorf("shutdown should be responded: %s", buf.String())
+	}
+}
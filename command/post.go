@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/lint"
 	"github.com/minodisk/qiitactl/model"
 )
 
@@ -81,6 +82,23 @@ func printPost(w io.Writer, post model.Post) (err error) {
 	return
 }
 
+type ShowDraftsRunner struct{}
+
+// ShowDrafts outputs your drafts in local which aren't published yet to stdout.
+func (r ShowDraftsRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	posts, err := model.FetchDrafts()
+	if err != nil {
+		return
+	}
+	for _, post := range posts {
+		_, err = fmt.Fprintf(w, "%s %s\n", post.Path, post.Title)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 type FetchPostRunner struct {
 	ID   *string
 	File **os.File
@@ -96,7 +114,23 @@ func (r FetchPostRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err er
 	if err != nil {
 		return
 	}
-	err = post.Save(nil)
+	clean, err := post.SaveMerged(nil)
+	if err != nil {
+		return
+	}
+	if !clean {
+		err = printConflicts(w, []string{post.Path})
+	}
+	return
+}
+
+func printConflicts(w io.Writer, paths []string) (err error) {
+	for _, path := range paths {
+		_, err = fmt.Fprintf(w, "conflict: %s\n", path)
+		if err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -106,6 +140,15 @@ func getID(id string, file *os.File) (i string, err error) {
 		return
 	}
 	if file != nil {
+		index, err := model.LoadIndex()
+		if err != nil {
+			return "", err
+		}
+		if entry, ok := index.Lookup(file.Name()); ok && !entry.Invalid {
+			i = entry.ID
+			return i, nil
+		}
+
 		post, err := model.NewPostWithOSFile(file)
 		if err != nil {
 			return "", err
@@ -118,7 +161,10 @@ func getID(id string, file *os.File) (i string, err error) {
 	return
 }
 
-type FetchPostsRunner struct{}
+type FetchPostsRunner struct {
+	WithAssets *bool
+	LocalLinks *bool
+}
 
 // FetchPosts fetches your posts from Qiita to current working directory.
 func (r FetchPostsRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
@@ -126,7 +172,7 @@ func (r FetchPostsRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err e
 	if err != nil {
 		return
 	}
-	err = posts.Save()
+	err = r.save(posts, w)
 	if err != nil {
 		return
 	}
@@ -141,7 +187,28 @@ func (r FetchPostsRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err e
 		if err != nil {
 			return
 		}
-		err = posts.Save()
+		err = r.save(posts, w)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (r FetchPostsRunner) save(posts model.Posts, w io.Writer) (err error) {
+	conflicts, err := posts.SaveMerged()
+	if err != nil {
+		return
+	}
+	err = printConflicts(w, conflicts)
+	if err != nil {
+		return
+	}
+	if !*r.WithAssets {
+		return
+	}
+	for i := range posts {
+		err = mirrorAssets(&posts[i], *r.LocalLinks)
 		if err != nil {
 			return
 		}
@@ -153,6 +220,7 @@ type CreatePostRunner struct {
 	File  **os.File
 	Tweet *bool
 	Gist  *bool
+	AssetOptions
 }
 
 // CreatePost creates a new post in Qiita with a specified file.
@@ -166,46 +234,173 @@ func (r CreatePostRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err e
 	if err != nil {
 		return
 	}
+	err = createPost(c, o.Error, &post, opts, r.AssetOptions)
+	return
+}
+
+// createPost creates the post in Qiita unless it has problems which block sending it,
+// and saves the file and the snapshot of the created post.
+func createPost(c api.Client, ew io.Writer, post *model.Post, opts model.CreationOptions, a AssetOptions) (err error) {
+	err = lint.Blocking(*post)
+	if err != nil {
+		return
+	}
+	warnUnknownKeys(ew, *post)
+	body := post.Body
+	post.Body, err = a.processAssets(*post)
+	if err != nil {
+		return
+	}
 	err = post.Create(c, opts)
+	remote := *post
+	post.Body = body
 	if err != nil {
 		return
 	}
 	err = post.Save(nil)
+	if err != nil {
+		return
+	}
+	err = remote.SaveBase()
 	return
 }
 
 type UpdatePostRunner struct {
-	File **os.File
+	File   **os.File
+	Force  *bool
+	Rebase *bool
+	AssetOptions
 }
 
 // UpdatePost updates your post in Qiita with a specified file.
 func (r UpdatePostRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
-	post, err := model.NewPostWithOSFile(*r.File)
+	_, err = updateFile(c, o.Error, (*r.File).Name(), *r.Force, *r.Rebase, r.AssetOptions)
+	return
+}
+
+// updateFile updates the post in Qiita with the file at path.
+// The post updated in Qiita after it is fetched isn't updated unless force is true,
+// and the remote change is merged into the file when rebase is true.
+func updateFile(c api.Client, ew io.Writer, path string, force, rebase bool, a AssetOptions) (post model.Post, err error) {
+	post, err = model.NewPostWithFile(path)
+	if err != nil {
+		return
+	}
+	if post.Draft {
+		err = model.DraftError{
+			Path: post.Path,
+		}
+		return
+	}
+	err = post.CheckResolved()
+	if err != nil {
+		return
+	}
+	if !force {
+		var remote model.Post
+		remote, err = post.CheckConflict(c)
+		if _, ok := err.(model.ConflictError); ok && rebase {
+			err = rebasePost(&post, remote)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	err = updatePost(c, ew, &post, a)
+	return
+}
+
+// updatePost updates the post in Qiita unless it has problems which block sending it,
+// and saves the file and the snapshot of the updated post.
+func updatePost(c api.Client, ew io.Writer, post *model.Post, a AssetOptions) (err error) {
+	err = lint.Blocking(*post)
+	if err != nil {
+		return
+	}
+	warnUnknownKeys(ew, *post)
+	body := post.Body
+	post.Body, err = a.processAssets(*post)
 	if err != nil {
 		return
 	}
 	err = post.Update(c)
+	remote := *post
+	post.Body = body
+	if err != nil {
+		return
+	}
+	err = post.Save(map[string]string{post.ID: post.Path})
+	if err != nil {
+		return
+	}
+	err = remote.SaveBase()
+	return
+}
+
+// warnUnknownKeys reports the keys in the meta of the post which aren't sent to Qiita to w.
+func warnUnknownKeys(w io.Writer, post model.Post) {
+	for _, key := range post.UnknownKeys() {
+		fmt.Fprintf(w, "warning: unknown key in meta isn't sent: %s\n", key)
+	}
+}
+
+// rebasePost merges the local changes into the remote post.
+// The merged file is saved and UnresolvedError is returned when any conflict is left.
+func rebasePost(post *model.Post, remote model.Post) (err error) {
+	base, err := model.LoadBase(post.ID)
 	if err != nil {
 		return
 	}
+	if post.Merge(base, remote) {
+		return
+	}
 	err = post.Save(nil)
+	if err != nil {
+		return
+	}
+	err = remote.SaveBase()
+	if err != nil {
+		return
+	}
+	err = model.AddConflict(post.Path)
+	if err != nil {
+		return
+	}
+	err = model.UnresolvedError{
+		Path: post.Path,
+	}
 	return
 }
 
 type DeletePostRunner struct {
-	File **os.File
+	File *string
+	Yes  *bool
+	In   io.Reader
 }
 
-// DeletePost deletes your post from Qiita with a specified file.
+// DeletePost deletes your post in Qiita after confirmation,
+// and moves the file into the trash.
 func (r DeletePostRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
-	post, err := model.NewPostWithOSFile(*r.File)
+	post, err := model.NewPostWithFile(*r.File)
 	if err != nil {
 		return
 	}
-	err = post.Delete(c)
+	if !*r.Yes {
+		var yes bool
+		yes, err = confirm(r.In, w, fmt.Sprintf("Delete %s in Qiita?", post.Title))
+		if err != nil {
+			return
+		}
+		if !yes {
+			_, err = fmt.Fprintln(w, "canceled")
+			return
+		}
+	}
+	err = post.MoveToTrash(c)
 	if err != nil {
 		return
 	}
-	err = post.Save(nil)
+	_, err = fmt.Fprintf(w, "moved %s into the trash: restore it with `qiitactl restore %s`\n", post.Path, post.ID)
 	return
 }
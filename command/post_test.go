@@ -2,6 +2,8 @@ package command_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +12,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -164,6 +167,7 @@ team: null
 	if err != nil {
 		t.Fatal(err)
 	}
+	saveBase(t, "mine/2000/01/01/Example Title.md")
 
 	testutil.ShouldExistFile(t, 1)
 
@@ -203,6 +207,277 @@ team: null
 	}
 }
 
+func TestFetchPostKeepsLocalAnnotations(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItem(mux)
+	serverMine := httptest.NewServer(mux)
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	err = os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+# TODO: ask for another review
+reviewers:
+- minodisk
+team: null
+-->
+
+# Example old title
+
+## Example old body`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveBase(t, "mine/2000/01/01/Example Title.md")
+
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, os.Stdout, errBuf)
+	app.Run([]string{"qiitactl", "fetch", "post", "-i", "4bd431809afb1bb99e4f"})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+
+	testutil.ShouldExistFile(t, 1)
+
+	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := string(b)
+	expected := `<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+# TODO: ask for another review
+reviewers:
+- minodisk
+team: null
+-->
+
+# Example Title
+
+## Example body`
+	if actual != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, actual))
+	}
+}
+
+func TestFetchPostMergesLocalChanges(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	remoteBody := "## A\n\na\n\n## B\n\nb"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/items/4bd431809afb1bb99e4f", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := json.Marshal(map[string]interface{}{
+			"body":       remoteBody,
+			"created_at": "2000-01-01T00:00:00+00:00",
+			"id":         "4bd431809afb1bb99e4f",
+			"tags":       []map[string]string{{"name": "Go"}},
+			"title":      "Example Title",
+			"updated_at": "2000-01-01T00:00:00+00:00",
+			"url":        "https://qiita.com/yaotti/items/4bd431809afb1bb99e4f",
+		})
+		w.Write(b)
+	})
+	serverMine := httptest.NewServer(mux)
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	fetch := func() string {
+		buf := bytes.NewBuffer([]byte{})
+		errBuf := bytes.NewBuffer([]byte{})
+		app := command.New(inf, client, buf, errBuf)
+		app.Run([]string{"qiitactl", "fetch", "post", "-i", "4bd431809afb1bb99e4f"})
+		if errBuf.Len() != 0 {
+			t.Fatal(errBuf.String())
+		}
+		return buf.String()
+	}
+	read := func() string {
+		b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	header := `<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Go
+team: null
+-->
+
+# Example Title
+
+`
+
+	fetch()
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(header+"## A\n\na local\n\n## B\n\nb"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remoteBody = "## A\n\na\n\n## B\n\nb remote"
+	out := fetch()
+	if out != "" {
+		t.Errorf("clean merge shouldn't report conflicts: %s", out)
+	}
+	expected := header + "## A\n\na local\n\n## B\n\nb remote"
+	if actual := read(); actual != expected {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+
+	remoteBody = "## A\n\na remote\n\n## B\n\nb remote"
+	out = fetch()
+	if out != "conflict: mine/2000/01/01/Example Title.md\n" {
+		t.Errorf("wrong output: %s", out)
+	}
+	expected = header + `## A
+
+<<<<<<< local
+a local
+=======
+a remote
+>>>>>>> remote
+
+## B
+
+b remote`
+	if actual := read(); actual != expected {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "resolve", "mine/2000/01/01/Example Title.md"})
+	if errBuf.String() != "mine/2000/01/01/Example Title.md still has conflict markers\n" {
+		t.Errorf("wrong error: %s", errBuf.String())
+	}
+}
+
+// saveBase saves the file at path as the snapshot of the last fetch.
+func saveBase(t *testing.T, path string) {
+	post, err := model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = post.SaveBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchPostWithoutBaseKeepsLocalChanges(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItem(mux)
+	serverMine := httptest.NewServer(mux)
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	header := `<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+team: null
+-->
+
+# Example Title
+
+`
+	err = os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file is fetched by an older version, which saves no snapshot.
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(header+"## Example body\n\nlocal edit"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "fetch", "post", "-i", "4bd431809afb1bb99e4f"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	if buf.String() != "conflict: mine/2000/01/01/Example Title.md\n" {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := header + `## Example body
+<<<<<<< local
+
+local edit
+=======
+>>>>>>> remote`
+	if string(b) != expected {
+		t.Errorf("the local changes should be kept:\n%s", testutil.Diff(expected, string(b)))
+	}
+}
+
 func TestShowPostWithID(t *testing.T) {
 	testutil.CleanUp()
 	defer testutil.CleanUp()
@@ -615,6 +890,129 @@ team:
 	}()
 }
 
+func TestFetchPostsWithAssets(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	var sentBody string
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/api/v2/authenticated_user/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Total-Count", "1")
+		fmt.Fprintf(w, `[
+			{
+				"body": "![diagram](%s/images/a.png)",
+				"created_at": "2000-01-01T00:00:00+00:00",
+				"id": "4bd431809afb1bb99e4f",
+				"tags": [{"name": "Go"}],
+				"title": "Example Title",
+				"updated_at": "2000-01-01T00:00:00+00:00",
+				"url": "https://qiita.com/yaotti/items/4bd431809afb1bb99e4f"
+			}
+		]`, server.URL)
+	})
+	mux.HandleFunc("/api/v2/teams", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("/api/v2/items/4bd431809afb1bb99e4f", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`{"id": "4bd431809afb1bb99e4f", "updated_at": "2000-01-01T00:00:00+00:00"}`))
+			return
+		}
+		defer r.Body.Close()
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			testutil.ResponseError(w, 500, err)
+			return
+		}
+		var post model.Post
+		err = json.Unmarshal(b, &post)
+		if err != nil {
+			testutil.ResponseError(w, 500, err)
+			return
+		}
+		sentBody = post.Body
+		w.Write(b)
+	})
+	mux.HandleFunc("/images/a.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+	})
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, os.Stdout, errBuf)
+	app.Run([]string{"qiitactl", "fetch", "posts", "--with-assets", "--local-links"})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+
+	testutil.ShouldExistFile(t, 1)
+
+	sum := sha256.Sum256([]byte(server.URL + "/images/a.png"))
+	local := "assets/" + hex.EncodeToString(sum[:]) + ".png"
+	_, err = os.Stat("mine/2000/01/01/" + local)
+	if err != nil {
+		t.Fatalf("image should be downloaded: %s", err)
+	}
+	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := string(b)
+	expected := fmt.Sprintf(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Go
+assets:
+  %s: %s/images/a.png
+team: null
+-->
+
+# Example Title
+
+![diagram](%s)`, local, server.URL, local)
+	if actual != expected {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+
+	// The links are already local when the post is fetched again.
+	app.Run([]string{"qiitactl", "fetch", "posts", "--with-assets", "--local-links"})
+	e = errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	b, err = ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != expected {
+		t.Errorf("the recorded assets should be kept when fetched again:\n%s", testutil.Diff(expected, string(b)))
+	}
+
+	app.Run([]string{"qiitactl", "update", "post", "mine/2000/01/01/Example Title.md"})
+	e = errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	if sentBody != fmt.Sprintf("![diagram](%s/images/a.png)", server.URL) {
+		t.Errorf("local links shouldn't be sent: %s", sentBody)
+	}
+}
+
 func TestFetchPostsErrors(t *testing.T) {
 	testutil.CleanUp()
 	defer testutil.CleanUp()
@@ -785,105 +1183,362 @@ team: null
 		t.Fatal(string(e))
 	}
 
-	testutil.ShouldExistFile(t, 1)
+	testutil.ShouldExistFile(t, 1)
+
+	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := string(b)
+	expected := `<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2016-02-01T21:51:42+09:00
+updated_at: 2016-02-01T21:51:42+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+- NewTag:
+  - "1.0"
+team: null
+-->
+
+# Example Edited Title
+
+## Example Edited Body`
+	if actual != expected {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+}
+
+func TestCreatePostErrorWithNoServer(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	serverMine := httptest.NewServer(mux)
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		switch subDomain {
+		case "":
+			url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		default:
+			log.Fatalf("wrong sub domain \"%s\"", subDomain)
+		}
+		return
+	}, inf)
+
+	testutil.ShouldExistFile(t, 0)
+
+	err = os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+id: ""
+url: ""
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+- NewTag:
+  - "1.0"
+team: null
+-->
+
+# Example Edited Title
+
+## Example Edited Body`), 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.ShouldExistFile(t, 1)
+
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, os.Stdout, errBuf)
+	app.Run([]string{"qiitactl", "create", "post", "mine/2000/01/01/Example Title.md"})
+	e := errBuf.Bytes()
+	if len(e) == 0 {
+		t.Fatal("error should occur")
+	}
+
+	testutil.ShouldExistFile(t, 1)
+}
+
+func TestUpdatePost(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItem(mux)
+	serverMine := httptest.NewServer(mux)
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		switch subDomain {
+		case "":
+			url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		default:
+			log.Fatalf("wrong sub domain \"%s\"", subDomain)
+		}
+		return
+	}, inf)
+
+	testutil.ShouldExistFile(t, 0)
+
+	err = os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+- NewTag:
+  - "1.0"
+team: null
+-->
+
+# Example Edited Title
+
+## Example Edited Body`), 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.ShouldExistFile(t, 1)
+
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, os.Stdout, errBuf)
+	app.Run([]string{"qiitactl", "update", "post", "mine/2000/01/01/Example Title.md"})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+
+	testutil.ShouldExistFile(t, 1)
+
+	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := string(b)
+	expected := `<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2016-02-01T21:51:42+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+- NewTag:
+  - "1.0"
+team: null
+-->
+
+# Example Edited Title
+
+## Example Edited Body`
+	if actual != expected {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+}
+
+func TestUpdatePostWithConflict(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItem(mux)
+	patched := 0
+	serverMine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			patched++
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	err = os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 1999-12-31T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+team: null
+-->
+
+# Example Title
+
+## Example Edited Body`), 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		errBuf := bytes.NewBuffer([]byte{})
+		app := command.New(inf, client, bytes.NewBuffer([]byte{}), errBuf)
+		app.Run(append([]string{"qiitactl", "update", "post"}, args...))
+		return errBuf.String()
+	}
+
+	e := run("mine/2000/01/01/Example Title.md")
+	expected := `conflict: mine/2000/01/01/Example Title.md is based on the post updated at 1999-12-31T09:00:00+09:00, but it was updated at 2000-01-01T09:00:00+09:00 in Qiita:
+- ## Example Edited Body
++ ## Example body
+`
+	if e != expected {
+		t.Errorf("wrong error:\n%s", testutil.Diff(expected, e))
+	}
+	if patched != 0 {
+		t.Fatal("post shouldn't be patched when conflicted")
+	}
 
+	e = run("--rebase", "mine/2000/01/01/Example Title.md")
+	if e != "mine/2000/01/01/Example Title.md has conflicts: resolve them and run `qiitactl resolve mine/2000/01/01/Example Title.md`\n" {
+		t.Errorf("wrong error: %s", e)
+	}
 	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
 	if err != nil {
 		t.Fatal(err)
 	}
-
 	actual := string(b)
-	expected := `<!--
+	expected = `<!--
 id: 4bd431809afb1bb99e4f
 url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
-created_at: 2016-02-01T21:51:42+09:00
-updated_at: 2016-02-01T21:51:42+09:00
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
 private: false
 coediting: false
 tags:
 - Ruby:
   - 0.0.1
-- NewTag:
-  - "1.0"
 team: null
 -->
 
-# Example Edited Title
+# Example Title
 
-## Example Edited Body`
+<<<<<<< local
+## Example Edited Body
+=======
+## Example body
+>>>>>>> remote`
 	if actual != expected {
 		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
 	}
-}
-
-func TestCreatePostErrorWithNoServer(t *testing.T) {
-	testutil.CleanUp()
-	defer testutil.CleanUp()
 
-	mux := http.NewServeMux()
-	serverMine := httptest.NewServer(mux)
-	defer serverMine.Close()
-	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
-	if err != nil {
-		log.Fatal(err)
+	e = run("mine/2000/01/01/Example Title.md")
+	if e != "mine/2000/01/01/Example Title.md has conflicts: resolve them and run `qiitactl resolve mine/2000/01/01/Example Title.md`\n" {
+		t.Errorf("wrong error: %s", e)
 	}
-	client := api.NewClient(func(subDomain, path string) (url string) {
-		switch subDomain {
-		case "":
-			url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
-		default:
-			log.Fatalf("wrong sub domain \"%s\"", subDomain)
-		}
-		return
-	}, inf)
-
-	testutil.ShouldExistFile(t, 0)
-
-	err = os.MkdirAll("mine/2000/01/01", 0755)
-	if err != nil {
-		t.Fatal(err)
+	if patched != 0 {
+		t.Fatal("post shouldn't be patched with conflict markers")
 	}
+
 	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
-id: ""
-url: ""
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
 created_at: 2000-01-01T09:00:00+09:00
-updated_at: 2000-01-01T09:00:00+09:00
+updated_at: 1999-12-31T09:00:00+09:00
 private: false
 coediting: false
 tags:
 - Ruby:
   - 0.0.1
-- NewTag:
-  - "1.0"
 team: null
 -->
 
-# Example Edited Title
+# Example Title
 
 ## Example Edited Body`), 0664)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	testutil.ShouldExistFile(t, 1)
-
+	buf := bytes.NewBuffer([]byte{})
 	errBuf := bytes.NewBuffer([]byte{})
-	app := command.New(inf, client, os.Stdout, errBuf)
-	app.Run([]string{"qiitactl", "create", "post", "mine/2000/01/01/Example Title.md"})
-	e := errBuf.Bytes()
-	if len(e) == 0 {
-		t.Fatal("error should occur")
+	app := command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "resolve"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	if buf.String() != "resolved mine/2000/01/01/Example Title.md\n" {
+		t.Errorf("wrong output: %s", buf.String())
 	}
 
-	testutil.ShouldExistFile(t, 1)
+	e = run("--force", "mine/2000/01/01/Example Title.md")
+	if e != "" {
+		t.Fatal(e)
+	}
+	if patched != 1 {
+		t.Errorf("post should be patched with --force: %d", patched)
+	}
 }
 
-func TestUpdatePost(t *testing.T) {
+func TestUpdatePostWithLocalImage(t *testing.T) {
 	testutil.CleanUp()
 	defer testutil.CleanUp()
 
+	var sentBody string
 	mux := http.NewServeMux()
-	handleItem(mux)
+	mux.HandleFunc("/api/v2/items/4bd431809afb1bb99e4f", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`{"id": "4bd431809afb1bb99e4f", "updated_at": "2000-01-01T00:00:00+00:00"}`))
+			return
+		}
+		defer r.Body.Close()
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			testutil.ResponseError(w, 500, err)
+			return
+		}
+		var post model.Post
+		err = json.Unmarshal(b, &post)
+		if err != nil {
+			testutil.ResponseError(w, 500, err)
+			return
+		}
+		sentBody = post.Body
+		w.Write(b)
+	})
 	serverMine := httptest.NewServer(mux)
 	defer serverMine.Close()
 	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
@@ -891,22 +1546,19 @@ func TestUpdatePost(t *testing.T) {
 		log.Fatal(err)
 	}
 	client := api.NewClient(func(subDomain, path string) (url string) {
-		switch subDomain {
-		case "":
-			url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
-		default:
-			log.Fatalf("wrong sub domain \"%s\"", subDomain)
-		}
+		url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
 		return
 	}, inf)
 
-	testutil.ShouldExistFile(t, 0)
-
-	err = os.MkdirAll("mine/2000/01/01", 0755)
+	err = os.MkdirAll("mine/2000/01/01/images", 0755)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+	err = ioutil.WriteFile("mine/2000/01/01/images/diagram.png", []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := `<!--
 id: 4bd431809afb1bb99e4f
 url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
 created_at: 2000-01-01T09:00:00+09:00
@@ -916,56 +1568,36 @@ coediting: false
 tags:
 - Ruby:
   - 0.0.1
-- NewTag:
-  - "1.0"
 team: null
 -->
 
-# Example Edited Title
+# Example Title
 
-## Example Edited Body`), 0664)
+![diagram](./images/diagram.png)`
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(content), 0664)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	testutil.ShouldExistFile(t, 1)
-
 	errBuf := bytes.NewBuffer([]byte{})
 	app := command.New(inf, client, os.Stdout, errBuf)
-	app.Run([]string{"qiitactl", "update", "post", "mine/2000/01/01/Example Title.md"})
+	app.Run([]string{"qiitactl", "update", "post", "mine/2000/01/01/Example Title.md", "--upload-dir", "foo/public", "--upload-url", "https://static.example.com"})
 	e := errBuf.Bytes()
 	if len(e) != 0 {
 		t.Fatal(string(e))
 	}
 
-	testutil.ShouldExistFile(t, 1)
+	expected := "![diagram](https://static.example.com/ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.png)"
+	if sentBody != expected {
+		t.Errorf("wrong sent body:\n%s", testutil.Diff(expected, sentBody))
+	}
 
 	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	actual := string(b)
-	expected := `<!--
-id: 4bd431809afb1bb99e4f
-url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
-created_at: 2000-01-01T09:00:00+09:00
-updated_at: 2016-02-01T21:51:42+09:00
-private: false
-coediting: false
-tags:
-- Ruby:
-  - 0.0.1
-- NewTag:
-  - "1.0"
-team: null
--->
-
-# Example Edited Title
-
-## Example Edited Body`
-	if actual != expected {
-		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	if string(b) != content {
+		t.Errorf("local file shouldn't be changed:\n%s", testutil.Diff(content, string(b)))
 	}
 }
 
@@ -1083,21 +1715,37 @@ team: null
 
 	testutil.ShouldExistFile(t, 1)
 
+	buf := bytes.NewBuffer([]byte{})
 	errBuf := bytes.NewBuffer([]byte{})
-	app := command.New(inf, client, os.Stdout, errBuf)
+	app := command.New(inf, client, buf, errBuf)
+	app.DeletePostRunner.In = strings.NewReader("n\n")
 	app.Run([]string{"qiitactl", "delete", "post", "mine/2000/01/01/Example Title.md"})
 	e := errBuf.Bytes()
 	if len(e) != 0 {
 		t.Fatal(string(e))
 	}
-
+	if buf.String() != "Delete Example Edited Title in Qiita? [y/N]: canceled\n" {
+		t.Errorf("wrong output: %s", buf.String())
+	}
 	testutil.ShouldExistFile(t, 1)
 
-	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	buf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "delete", "post", "--yes", "mine/2000/01/01/Example Title.md"})
+	e = errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	if buf.String() != "moved mine/2000/01/01/Example Title.md into the trash: restore it with `qiitactl restore 4bd431809afb1bb99e4f`\n" {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+
+	testutil.ShouldExistFile(t, 0)
+
+	b, err := ioutil.ReadFile(".qiitactl/trash/4bd431809afb1bb99e4f/post.md")
 	if err != nil {
 		t.Fatal(err)
 	}
-
 	actual := string(b)
 	expected := `<!--
 id: 4bd431809afb1bb99e4f
@@ -1120,6 +1768,19 @@ team: null
 	if actual != expected {
 		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
 	}
+
+	trash, err := model.LoadTrash("4bd431809afb1bb99e4f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remote model.Post
+	err = json.Unmarshal(trash.Remote, &remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote.Title != "Example Title" || trash.Path != "mine/2000/01/01/Example Title.md" {
+		t.Errorf("wrong trash: %+v", trash)
+	}
 }
 
 func TestDeletePostErrorWithNoServer(t *testing.T) {
@@ -1175,7 +1836,7 @@ team: null
 
 	errBuf := bytes.NewBuffer([]byte{})
 	app := command.New(inf, client, os.Stdout, errBuf)
-	app.Run([]string{"qiitactl", "delete", "post", "mine/2000/01/01/Example Title.md"})
+	app.Run([]string{"qiitactl", "delete", "post", "--yes", "mine/2000/01/01/Example Title.md"})
 	e := errBuf.Bytes()
 	if len(e) == 0 {
 		t.Fatal("error should occur")
@@ -1482,3 +2143,197 @@ func handleAuthenticatedUserItemsWithTeam(mux *http.ServeMux) {
 		w.Write([]byte(body))
 	})
 }
+
+func TestFetchAndUpdatePostsWithLinks(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	items := map[string]string{
+		"00000000000000000001": `{"id": "00000000000000000001", "url": "https://qiita.com/foo/items/00000000000000000001", "title": "Part 1", "body": "Next: [part 2](https://qiita.com/foo/items/00000000000000000002)", "tags": [{"name": "Go"}], "created_at": "2024-05-01T00:00:00+09:00", "updated_at": "2024-05-01T00:00:00+09:00"}`,
+		"00000000000000000002": `{"id": "00000000000000000002", "url": "https://qiita.com/foo/items/00000000000000000002", "title": "Part 2", "body": "Prev: [part 1](https://qiita.com/foo/items/00000000000000000001#summary)", "tags": [{"name": "Go"}], "created_at": "2024-05-02T00:00:00+09:00", "updated_at": "2024-05-02T00:00:00+09:00"}`,
+	}
+	var sent string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/authenticated_user/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Total-Count", "2")
+		fmt.Fprintf(w, "[%s, %s]", items["00000000000000000001"], items["00000000000000000002"])
+	})
+	mux.HandleFunc("/api/v2/teams", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("/api/v2/items/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			var post model.Post
+			json.NewDecoder(r.Body).Decode(&post)
+			sent = post.Body
+		}
+		w.Write([]byte(items[strings.TrimPrefix(r.URL.Path, "/api/v2/items/")]))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, os.Stdout, errBuf)
+	app.Run([]string{"qiitactl", "fetch", "posts"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	for path, body := range map[string]string{
+		"mine/2024/05/01/Part 1.md": "Next: [part 2](../02/Part%202.md)",
+		"mine/2024/05/02/Part 2.md": "Prev: [part 1](../01/Part%201.md#summary)",
+	} {
+		post, err := model.NewPostWithFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.Body != body {
+			t.Errorf("wrong body of %s: %s", path, post.Body)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "status"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	if buf.Len() != 0 {
+		t.Errorf("the relative links shouldn't be reported as changes: %s", buf.String())
+	}
+
+	app = command.New(inf, client, os.Stdout, errBuf)
+	app.Run([]string{"qiitactl", "update", "post", "mine/2024/05/02/Part 2.md"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	if sent != "Prev: [part 1](https://qiita.com/foo/items/00000000000000000001#summary)" {
+		t.Errorf("the links should be resolved into the URLs: %s", sent)
+	}
+
+	err = ioutil.WriteFile("mine/draft.md", []byte("<!--\ndraft: true\ntags:\n- Go\n-->\n\n# Draft\n\nbody"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile("mine/2024/05/02/Part 2.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2024/05/02/Part 2.md", []byte(string(b)+"\n\n[draft](../../../draft.md)"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errBuf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, os.Stdout, errBuf)
+	err = app.Run([]string{"qiitactl", "update", "post", "mine/2024/05/02/Part 2.md"})
+	if _, ok := err.(model.LinkError); !ok {
+		t.Fatalf("LinkError should be returned: %v", err)
+	}
+	if !strings.Contains(errBuf.String(), "- ../../../draft.md: not published yet") {
+		t.Errorf("wrong error: %s", errBuf.String())
+	}
+}
+
+func TestFetchAndUpdatePostsWithIncludes(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	item := `{"id": "00000000000000000001", "url": "https://qiita.com/foo/items/00000000000000000001", "title": "Include", "body": "<!-- include: ../../../src/main.go lines=1 -->\n` + "```go:main.go\\npackage main\\n```" + `\n<!-- /include -->\n\nv1.2.3", "tags": [{"name": "Go"}], "created_at": "2024-05-01T00:00:00+09:00", "updated_at": "2024-05-01T00:00:00+09:00"}`
+	var sent string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/authenticated_user/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Total-Count", "1")
+		fmt.Fprintf(w, "[%s]", item)
+	})
+	mux.HandleFunc("/api/v2/teams", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("/api/v2/items/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			var post model.Post
+			json.NewDecoder(r.Body).Decode(&post)
+			sent = post.Body
+		}
+		w.Write([]byte(item))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	err = os.MkdirAll("mine/src", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/src/main.go", []byte("package main\n\nfunc main() {}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(".qiitactl", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(model.VariablesPath, []byte("version: 1.2.3\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, os.Stdout, errBuf)
+	app.Run([]string{"qiitactl", "fetch", "posts"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	path := "mine/2024/05/01/Include.md"
+	post, err := model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Body != "<!-- include: ../../../src/main.go lines=1 -->\n\nv1.2.3" {
+		t.Errorf("the block should be collapsed into the directive: %s", post.Body)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "status"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	if buf.Len() != 0 {
+		t.Errorf("the directive shouldn't be reported as a change: %s", buf.String())
+	}
+
+	post.Body = "<!-- include: ../../../src/main.go lines=3 -->\n\nv{{version}}"
+	err = post.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app = command.New(inf, client, os.Stdout, errBuf)
+	app.Run([]string{"qiitactl", "update", "post", path})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	if sent != "<!-- include: ../../../src/main.go lines=3 -->\n```go:main.go\nfunc main() {}\n```\n<!-- /include -->\n\nv1.2.3" {
+		t.Errorf("the body should be expanded: %s", sent)
+	}
+	post, err = model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Body != "<!-- include: ../../../src/main.go lines=3 -->\n\nv{{version}}" {
+		t.Errorf("the file shouldn't be changed: %s", post.Body)
+	}
+}
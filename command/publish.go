@@ -0,0 +1,161 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type PublishRunner struct {
+	File  **os.File
+	Due   *bool
+	Tweet *bool
+	Gist  *bool
+	AssetOptions
+}
+
+// Publish creates a new post in Qiita with a draft in local,
+// then records the ID of the post in the file.
+// With --due, it publishes every draft whose publish_at has passed.
+func (r PublishRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	opts := model.CreationOptions{
+		Tweet: *r.Tweet,
+		Gist:  *r.Gist,
+	}
+
+	if *r.Due {
+		err = publishDue(c, w, o.Error, opts, r.AssetOptions, time.Now())
+		return
+	}
+	if *r.File == nil {
+		err = fmt.Errorf("publish: filename or --due is required")
+		return
+	}
+
+	post, err := publishFile(c, o.Error, (*r.File).Name(), opts, r.AssetOptions)
+	if err != nil {
+		return
+	}
+	err = printPost(w, post)
+	return
+}
+
+// publishFile creates a new post in Qiita with the draft in the file at path.
+// The draft is read under the lock not to be published by publish --due or schedule at the same time.
+func publishFile(c api.Client, ew io.Writer, path string, opts model.CreationOptions, a AssetOptions) (post model.Post, err error) {
+	unlock, err := model.Lock(lockPublish)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	post, err = model.NewPostWithFile(path)
+	if err != nil {
+		return
+	}
+	if post.ID != "" {
+		err = fmt.Errorf("%s is already published as %s", post.Path, post.ID)
+		return
+	}
+	err = createPost(c, ew, &post, opts, a)
+	return
+}
+
+// publishDue publishes the drafts whose publish_at has passed.
+func publishDue(c api.Client, w, ew io.Writer, opts model.CreationOptions, a AssetOptions, now time.Time) (err error) {
+	drafts, err := model.FetchDrafts()
+	if err != nil {
+		return
+	}
+	err = publishDrafts(c, ew, drafts.Due(now), opts, a, func(post model.Post, err error) error {
+		if err != nil {
+			return err
+		}
+		return printPost(w, post)
+	})
+	return
+}
+
+// lockPublish is the name of the lock held while publishing drafts.
+const lockPublish = "publish"
+
+// publishDrafts publishes the drafts and calls fn with each draft and the error of publishing it.
+// Publishing stops when fn returns an error.
+// The drafts are published under the lock and re-read before publishing,
+// so that overlapping runs never publish a draft twice.
+func publishDrafts(c api.Client, ew io.Writer, drafts model.Posts, opts model.CreationOptions, a AssetOptions, fn func(post model.Post, err error) error) (err error) {
+	unlock, err := model.Lock(lockPublish)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	for _, draft := range drafts {
+		var post model.Post
+		post, err = model.NewPostWithFile(draft.Path)
+		if err == nil && post.ID != "" {
+			continue
+		}
+		if err == nil {
+			err = createPost(c, ew, &post, opts, a)
+		}
+		if err != nil {
+			post.Path = draft.Path
+		}
+		err = fn(post, err)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+type ScheduleRunner struct {
+	Interval *time.Duration
+	Tweet    *bool
+	Gist     *bool
+	AssetOptions
+
+	// Stop stops scheduling when it is closed.
+	// It is nil in the command line, so that schedule keeps running.
+	Stop <-chan struct{}
+}
+
+// Schedule keeps running and publishes drafts when their publish_at comes.
+// It wakes up at least every interval to find new drafts.
+// The errors while publishing, including the lock held by another process, are reported
+// and publishing is retried at the next wake-up.
+func (r ScheduleRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	opts := model.CreationOptions{
+		Tweet: *r.Tweet,
+		Gist:  *r.Gist,
+	}
+
+	for {
+		now := time.Now()
+		wait := *r.Interval
+		err = publishDue(c, w, o.Error, opts, r.AssetOptions, now)
+		if err == nil {
+			var drafts model.Posts
+			drafts, err = model.FetchDrafts()
+			if next := drafts.NextPublishAt(now); err == nil && next != nil && next.Sub(now) < wait {
+				wait = next.Sub(now)
+			}
+		}
+		if err != nil {
+			_, err = fmt.Fprintf(w, "%s\n", err)
+			if err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-r.Stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
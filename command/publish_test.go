@@ -0,0 +1,300 @@
+package command_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestPublish(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItems(mux)
+	serverMine := httptest.NewServer(mux)
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "generate", "file", "Example Title"})
+	path := strings.TrimSpace(buf.String())
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "\ndraft: true\n") {
+		t.Errorf("generated file should be a draft:\n%s", b)
+	}
+
+	buf.Reset()
+	app.Run([]string{"qiitactl", "show", "drafts"})
+	if buf.String() != path+" Example Title\n" {
+		t.Errorf("wrong drafts: %s", buf.String())
+	}
+
+	app.Run([]string{"qiitactl", "update", "post", path})
+	if !strings.Contains(errBuf.String(), "is a draft") {
+		t.Errorf("updating a draft should fail: %s", errBuf.String())
+	}
+	errBuf.Reset()
+
+	b = bytes.Replace(b, []byte("tags: []\n"), []byte("tags:\n- Go\n"), 1)
+	err = ioutil.WriteFile(path, append(b, []byte("## Example body")...), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	app.Run([]string{"qiitactl", "publish", path})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	if !strings.HasPrefix(buf.String(), "4bd431809afb1bb99e4f ") {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+
+	testutil.ShouldExistFile(t, 1)
+
+	b, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "draft:") {
+		t.Errorf("draft flag should be cleared:\n%s", b)
+	}
+	if !strings.Contains(string(b), "\nid: 4bd431809afb1bb99e4f\n") {
+		t.Errorf("ID should be recorded:\n%s", b)
+	}
+
+	buf.Reset()
+	app.Run([]string{"qiitactl", "show", "drafts"})
+	if buf.String() != "" {
+		t.Errorf("published post shouldn't be listed: %s", buf.String())
+	}
+
+	app.Run([]string{"qiitactl", "publish", path})
+	if !strings.Contains(errBuf.String(), "already published") {
+		t.Errorf("publishing twice should fail: %s", errBuf.String())
+	}
+}
+
+func TestPublishDue(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItems(mux)
+	serverMine := httptest.NewServer(mux)
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	err = os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	draft := `<!--
+id: ""
+url: ""
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Go
+draft: true
+publish_at: %s
+team: null
+-->
+
+# %s
+
+## Example body`
+	err = ioutil.WriteFile("mine/2000/01/01/Due.md", []byte(fmt.Sprintf(draft, "2000-01-01T10:00:00+09:00", "Due")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Future.md", []byte(fmt.Sprintf(draft, "2999-01-01T10:00:00+09:00", "Future")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+
+	unlock, err := model.Lock("publish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Run([]string{"qiitactl", "publish", "--due"})
+	if !strings.HasPrefix(errBuf.String(), "locked by another process") {
+		t.Errorf("overlapping run should fail: %s", errBuf.String())
+	}
+	unlock()
+	errBuf.Reset()
+
+	app.Run([]string{"qiitactl", "publish", "--due"})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	if !strings.HasSuffix(buf.String(), " Due\n") || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("only the due draft should be published: %s", buf.String())
+	}
+
+	b, err := ioutil.ReadFile("mine/2000/01/01/Due.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "\nid: 4bd431809afb1bb99e4f\n") || strings.Contains(string(b), "publish_at") {
+		t.Errorf("wrong published file:\n%s", b)
+	}
+
+	buf.Reset()
+	app.Run([]string{"qiitactl", "publish", "--due"})
+	if buf.String() != "" {
+		t.Errorf("published draft shouldn't be published again: %s", buf.String())
+	}
+
+	buf.Reset()
+	app.Run([]string{"qiitactl", "show", "drafts"})
+	if buf.String() != "mine/2000/01/01/Future.md Future\n" {
+		t.Errorf("wrong drafts: %s", buf.String())
+	}
+}
+
+func TestScheduleRetriesAfterErrors(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItems(mux)
+	failed := false
+	serverMine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && !failed {
+			failed = true
+			testutil.ResponseError(w, 500, errors.New("temporary failure"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	err = os.MkdirAll("mine", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/due.md", []byte("<!--\ntags:\n- Go\ndraft: true\npublish_at: 2000-01-01T10:00:00+09:00\n-->\n\n# Due\n\n## Example body"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	stop := make(chan struct{})
+	app.ScheduleRunner.Stop = stop
+	done := make(chan error)
+	go func() {
+		done <- app.Run([]string{"qiitactl", "schedule", "--interval", "50ms"})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		post, err := model.NewPostWithFile("mine/due.md")
+		if err == nil && post.ID != "" {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(stop)
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "temporary failure") || !strings.HasSuffix(lines[1], " Due") {
+		t.Errorf("the draft should be published after the error is reported: %s", buf.String())
+	}
+}
+
+func TestScheduleReportsLock(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	client := api.NewClient(nil, inf)
+	err := os.MkdirAll(model.DirWorkspace, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The lock is held by a running process.
+	err = ioutil.WriteFile(filepath.Join(model.DirWorkspace, "publish.lock"), []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	stop := make(chan struct{})
+	app.ScheduleRunner.Stop = stop
+	done := make(chan error)
+	go func() {
+		done <- app.Run([]string{"qiitactl", "schedule", "--interval", "20ms"})
+	}()
+	time.Sleep(150 * time.Millisecond)
+	close(stop)
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 2 {
+		t.Errorf("the lock should be reported on every wake-up: %s", buf.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "locked by another process") {
+			t.Errorf("wrong output: %s", line)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type PushRunner struct {
+	Concurrency *int
+	Force       *bool
+	AssetOptions
+}
+
+// Push updates all the posts whose files are modified since they were fetched or updated last time.
+// It goes on when some of the updates fail, and reports the result of each file.
+func (r PushRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	posts, err := model.FetchModifiedPosts()
+	if err != nil {
+		return
+	}
+
+	concurrency := *r.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	errs := make([]error, len(posts))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = r.push(c, o.Error, &posts[i])
+			}
+		}()
+	}
+	for i := range posts {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tPATH\tERROR")
+	failed := 0
+	for i, post := range posts {
+		if errs[i] != nil {
+			failed++
+			msg := strings.SplitN(errs[i].Error(), "\n", 2)[0]
+			fmt.Fprintf(tw, "failed\t%s\t%s\n", post.Path, msg)
+			continue
+		}
+		fmt.Fprintf(tw, "updated\t%s\t\n", post.Path)
+	}
+	err = tw.Flush()
+	if err != nil {
+		return
+	}
+	if failed > 0 {
+		err = fmt.Errorf("push: %d of %d posts failed to be updated", failed, len(posts))
+	}
+	return
+}
+
+func (r PushRunner) push(c api.Client, ew io.Writer, post *model.Post) (err error) {
+	err = post.CheckResolved()
+	if err != nil {
+		return
+	}
+	if !*r.Force {
+		_, err = post.CheckConflict(c)
+		if err != nil {
+			return
+		}
+	}
+	err = updatePost(c, ew, post, r.AssetOptions)
+	return
+}
@@ -0,0 +1,118 @@
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestPush(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	updatedAt := map[string]string{
+		"00000000000000000001": "2000-01-01T00:00:00+00:00",
+		"00000000000000000002": "2000-01-02T00:00:00+00:00",
+		"00000000000000000003": "2000-01-01T00:00:00+00:00",
+	}
+	patched := make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v2/items/")
+		switch r.Method {
+		case "GET":
+			fmt.Fprintf(w, `{"id": %q, "updated_at": %q}`, id, updatedAt[id])
+		case "PATCH":
+			patched <- id
+			var post model.Post
+			err := json.NewDecoder(r.Body).Decode(&post)
+			if err != nil {
+				testutil.ResponseError(w, 500, err)
+				return
+			}
+			b, _ := json.Marshal(post)
+			w.Write(b)
+		}
+	}))
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	for i, body := range []string{"modified", "modified", "base"} {
+		id := fmt.Sprintf("0000000000000000000%d", i+1)
+		path := filepath.Join("mine", fmt.Sprintf("%d.md", i+1))
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(fmt.Sprintf("<!--\nid: %s\nupdated_at: 2000-01-01T09:00:00+09:00\ntags:\n- Go\n-->\n\n# Title\n\n%s", id, body)), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = model.Post{
+			Title: "Title",
+			Body:  "base",
+			Meta:  model.Meta{ID: id, Tags: model.Tags{{Name: "Go"}}},
+		}.SaveBase()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A draft which is unpublished again keeps its ID, but shouldn't be pushed.
+	err = ioutil.WriteFile("mine/4.md", []byte("<!--\nid: 00000000000000000004\nupdated_at: 2000-01-01T09:00:00+09:00\ndraft: true\ntags:\n- Go\n-->\n\n# Title\n\nmodified"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = model.Post{
+		Title: "Title",
+		Body:  "base",
+		Meta:  model.Meta{ID: "00000000000000000004", Tags: model.Tags{{Name: "Go"}}},
+	}.SaveBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	err = app.Run([]string{"qiitactl", "push", "--concurrency", "2"})
+	if err == nil {
+		t.Fatal("error should be returned when any update fails")
+	}
+	if errBuf.String() != "push: 1 of 2 posts failed to be updated\n" {
+		t.Errorf("wrong error: %s", errBuf.String())
+	}
+	expected := `RESULT   PATH       ERROR
+updated  mine/1.md  
+failed   mine/2.md  conflict: mine/2.md is based on the post updated at 2000-01-01T09:00:00+09:00, but it was updated at 2000-01-02T09:00:00+09:00 in Qiita:
+`
+	if buf.String() != expected {
+		t.Errorf("wrong output:\n%s", testutil.Diff(expected, buf.String()))
+	}
+	close(patched)
+	var ids []string
+	for id := range patched {
+		ids = append(ids, id)
+	}
+	if len(ids) != 1 || ids[0] != "00000000000000000001" {
+		t.Errorf("wrong patched posts: %v", ids)
+	}
+}
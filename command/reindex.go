@@ -0,0 +1,21 @@
+package command
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type ReindexRunner struct{}
+
+// Reindex rebuilds the index of local files of posts from scratch.
+func (r ReindexRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	index, err := model.RebuildIndex()
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "indexed %d posts\n", len(index.Paths()))
+	return
+}
@@ -0,0 +1,67 @@
+package command_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestReindex(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	client := api.NewClient(nil, inf)
+
+	post := []byte(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Go
+team: null
+-->
+
+# Example Title
+
+## Example body`)
+	for _, dir := range []string{"mine/2000/01/01", "node_modules/foo"} {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(dir+"/Example Title.md", post, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "reindex"})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	if buf.String() != "indexed 1 posts\n" {
+		t.Errorf("wrong output: %q", buf.String())
+	}
+
+	index, err := model.LoadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := index.Paths()
+	if paths["4bd431809afb1bb99e4f"] != "mine/2000/01/01/Example Title.md" {
+		t.Errorf("wrong paths: %v", paths)
+	}
+}
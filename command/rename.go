@@ -0,0 +1,38 @@
+package command
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type RenameRunner struct {
+	DryRun *bool
+}
+
+// Rename moves the files of posts to the paths made with the path template of the workspace,
+// and reports the moves.
+func (r RenameRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	posts, err := model.FetchLocalPosts()
+	if err != nil {
+		return
+	}
+	for _, post := range posts {
+		from := post.Path
+		var to string
+		to, err = post.Rename(*r.DryRun)
+		if err != nil {
+			return
+		}
+		if to == from {
+			continue
+		}
+		_, err = fmt.Fprintf(w, "%s -> %s\n", from, to)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,214 @@
+package command_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestRename(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	client := api.NewClient(nil, inf)
+
+	err := os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+team: null
+-->
+
+# Example Title
+
+## Example body`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(".qiitactl", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(".qiitactl/config.yml", []byte("path_template: '{{.TeamID}}/{{index .Tags 0}}/{{.Slug}}.md'\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "rename", "--dry-run"})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	expected := "mine/2000/01/01/Example Title.md -> mine/Ruby/example-title.md\n"
+	if buf.String() != expected {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+	_, err = os.Stat("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Errorf("file shouldn't be moved in dry run")
+	}
+
+	buf.Reset()
+	app = command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "rename"})
+	e = errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	if buf.String() != expected {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+	testutil.ShouldExistFile(t, 1)
+	_, err = os.Stat("mine/Ruby/example-title.md")
+	if err != nil {
+		t.Errorf("file should be moved")
+	}
+
+	buf.Reset()
+	app.Run([]string{"qiitactl", "rename"})
+	if buf.String() != "" {
+		t.Errorf("file in place shouldn't be moved: %s", buf.String())
+	}
+}
+
+func writeRenamedPost(t *testing.T, meta string) {
+	err := os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Ruby:
+  - 0.0.1
+`+meta+`team: null
+-->
+
+# Example Title
+
+![diagram](assets/diagram.png)`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(".qiitactl", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(".qiitactl/config.yml", []byte("path_template: '{{.TeamID}}/{{index .Tags 0}}/{{.Slug}}.md'\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runRename(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, api.NewClient(nil, inf), buf, errBuf)
+	app.Run([]string{"qiitactl", "rename"})
+	e := errBuf.Bytes()
+	if len(e) != 0 {
+		t.Fatal(string(e))
+	}
+	if buf.String() != "mine/2000/01/01/Example Title.md -> mine/Ruby/example-title.md\n" {
+		t.Errorf("wrong output: %s", buf.String())
+	}
+}
+
+func TestRenameMovesAssets(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	writeRenamedPost(t, "assets:\n  assets/diagram.png: https://example.com/diagram.png\n")
+	err := os.MkdirAll("mine/2000/01/01/assets", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/assets/diagram.png", []byte("png"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runRename(t)
+
+	b, err := ioutil.ReadFile("mine/Ruby/assets/diagram.png")
+	if err != nil {
+		t.Fatal("mirrored image should be moved with the post")
+	}
+	if string(b) != "png" {
+		t.Errorf("wrong image: %s", b)
+	}
+	_, err = os.Stat("mine/2000/01/01/assets")
+	if !os.IsNotExist(err) {
+		t.Errorf("the emptied assets directory should be removed")
+	}
+}
+
+func TestRenameMovesConflict(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	writeRenamedPost(t, "")
+	err := model.AddConflict("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runRename(t)
+
+	conflicts, err := model.LoadConflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := conflicts.Paths()
+	if len(paths) != 1 || paths[0] != "mine/Ruby/example-title.md" {
+		t.Errorf("the record of the conflicts should be moved: %v", paths)
+	}
+}
+
+func TestRenameMovesPreview(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	writeRenamedPost(t, "draft: true\n")
+	previews := model.Previews{
+		"mine/2000/01/01/Example Title.md": "c686397e4a0f4f11683d",
+	}
+	err := previews.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runRename(t)
+
+	previews, err = model.LoadPreviews()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 1 || previews["mine/Ruby/example-title.md"] != "c686397e4a0f4f11683d" {
+		t.Errorf("the record of the preview should be moved: %v", previews)
+	}
+}
@@ -0,0 +1,269 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/markdown"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type RenderRunner struct {
+	File **os.File
+}
+
+// Render prints the body of a post rendered into HTML without Qiita.
+// The variables and the include directives are expanded as far as possible.
+func (r RenderRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	post, err := model.NewPostWithOSFile(*r.File)
+	if err != nil {
+		return
+	}
+	body, _ := post.Expand()
+	_, err = io.WriteString(w, markdown.Render(body))
+	return
+}
+
+type PreviewRunner struct {
+	File *string
+	Addr *string
+
+	// Stop stops the server when it is closed.
+	// It is nil in the command line, so that the server keeps running.
+	Stop <-chan struct{}
+}
+
+// Preview serves the posts in current working directory rendered into HTML.
+// The pages are reloaded when the files are saved.
+func (r PreviewRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	ln, err := net.Listen("tcp", *r.Addr)
+	if err != nil {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", servePreviewIndex)
+	mux.HandleFunc("/posts/", servePreviewPost)
+	mux.HandleFunc("/versions/", servePreviewVersion)
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-r.Stop
+		server.Close()
+	}()
+
+	u := fmt.Sprintf("http://%s/", ln.Addr())
+	if *r.File != "" {
+		u += previewPath(*r.File)
+	}
+	_, err = fmt.Fprintf(w, "previewing at %s\n", u)
+	if err != nil {
+		ln.Close()
+		return
+	}
+	err = server.Serve(ln)
+	if err == http.ErrServerClosed {
+		err = nil
+	}
+	return
+}
+
+// previewPath returns the path of the page of the file in the preview server.
+func previewPath(path string) string {
+	u := url.URL{Path: "posts/" + filepath.ToSlash(filepath.Clean(path))}
+	return u.EscapedPath()
+}
+
+// previewFileExtensions are the extensions of the files next to posts which are served as they are.
+var previewFileExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".svg":  true,
+	".webp": true,
+	".bmp":  true,
+	".ico":  true,
+}
+
+// localPath returns the path of the file requested with the URL whose path starts with prefix.
+// The files outside current working directory and the hidden files like .env and .qiitactl aren't allowed.
+func localPath(req *http.Request, prefix string) (path string, ok bool) {
+	path = filepath.Clean(filepath.FromSlash(strings.TrimPrefix(req.URL.Path, prefix)))
+	if path == "." || filepath.IsAbs(path) {
+		return
+	}
+	for _, name := range strings.Split(path, string(filepath.Separator)) {
+		if strings.HasPrefix(name, ".") {
+			return
+		}
+	}
+	ok = true
+	return
+}
+
+func fileVersion(path string) (version string, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(b)
+	version = hex.EncodeToString(sum[:])
+	return
+}
+
+var previewIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>qiitactl preview</title>
+</head>
+<body>
+<ul>
+{{range .}}<li><a href="/{{.URL}}">{{.Title}}</a> {{.Path}}</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+func servePreviewIndex(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	index, err := model.UpdateIndex()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var paths []string
+	for path, entry := range index {
+		if !entry.Invalid {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	type item struct {
+		Title string
+		Path  string
+		URL   template.URL
+	}
+	var items []item
+	for _, path := range paths {
+		post, err := model.NewPostWithFile(path)
+		if err != nil {
+			continue
+		}
+		items = append(items, item{
+			Title: post.Title,
+			Path:  path,
+			URL:   template.URL(previewPath(path)),
+		})
+	}
+	err = previewIndexTemplate.Execute(w, items)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var previewPostTemplate = template.Must(template.New("post").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Post.Title}}</title>
+<style>
+body { max-width: 48em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.7; }
+.tags span { margin-right: .5em; padding: 0 .4em; background: #eee; }
+.code-frame, pre { background: #f6f6f6; overflow: auto; }
+.code-lang { padding: .2em .6em; background: #ddd; }
+pre { margin: 0; padding: .6em; }
+.note { margin: 1em 0; padding: .2em 1em; border-left: 4px solid; }
+.note.info { border-color: #4a90d9; }
+.note.warn { border-color: #e6a23c; }
+.note.alert { border-color: #d9534f; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: .2em .6em; }
+</style>
+<script>MathJax = {tex: {inlineMath: [["\\(", "\\)"]]}};</script>
+<script async src="https://cdn.jsdelivr.net/npm/mathjax@3/es5/tex-chtml.js"></script>
+<script src="https://cdn.jsdelivr.net/npm/mermaid/dist/mermaid.min.js"></script>
+</head>
+<body>
+<h1>{{.Post.Title}}</h1>
+<p class="tags">{{range .Post.Tags}}<span>{{.Name}}</span>{{end}}</p>
+{{.Body}}
+<script>
+if (window.mermaid) mermaid.initialize({startOnLoad: true});
+setInterval(function () {
+  fetch({{.VersionURL}}).then(function (res) { return res.text(); }).then(function (version) {
+    if (version !== {{.Version}}) location.reload();
+  });
+}, 1000);
+</script>
+</body>
+</html>
+`))
+
+// servePreviewPost serves the page of a post, or an image next to it.
+func servePreviewPost(w http.ResponseWriter, req *http.Request) {
+	path, ok := localPath(req, "/posts/")
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if previewFileExtensions[ext] {
+		http.ServeFile(w, req, path)
+		return
+	}
+	if ext != ".md" {
+		http.NotFound(w, req)
+		return
+	}
+	version, err := fileVersion(path)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	post, err := model.NewPostWithFile(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, _ := post.Expand()
+	err = previewPostTemplate.Execute(w, map[string]interface{}{
+		"Post":       post,
+		"Body":       template.HTML(markdown.Render(body)),
+		"Version":    version,
+		"VersionURL": "/versions/" + strings.TrimPrefix(previewPath(path), "posts/"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// servePreviewVersion serves the version of the file of a post, which changes when the file is saved.
+func servePreviewVersion(w http.ResponseWriter, req *http.Request) {
+	path, ok := localPath(req, "/versions/")
+	if !ok || filepath.Ext(path) != ".md" {
+		http.NotFound(w, req)
+		return
+	}
+	version, err := fileVersion(path)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+	io.WriteString(w, version)
+}
@@ -0,0 +1,159 @@
+package command_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func writePreviewPost(t *testing.T, body string) {
+	err := os.MkdirAll("mine/2000/01/01", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/Example Title.md", []byte(`<!--
+id: 4bd431809afb1bb99e4f
+tags:
+- Go
+-->
+
+# Example Title
+
+`+body), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	writePreviewPost(t, ":::note warn\nBe **careful**.\n:::")
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, api.NewClient(nil, inf), buf, errBuf)
+	app.Run([]string{"qiitactl", "render", "mine/2000/01/01/Example Title.md"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	expected := `<div class="note warn">
+<p>Be <strong>careful</strong>.</p>
+</div>
+`
+	if buf.String() != expected {
+		t.Errorf("wrong html:\n%s", testutil.Diff(expected, buf.String()))
+	}
+}
+
+func TestPreview(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	writePreviewPost(t, "## Example Body")
+
+	pr, pw := io.Pipe()
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, api.NewClient(nil, inf), pw, errBuf)
+	stop := make(chan struct{})
+	app.PreviewRunner.Stop = stop
+	done := make(chan error)
+	go func() {
+		done <- app.Run([]string{"qiitactl", "preview", "--addr", "127.0.0.1:0", "mine/2000/01/01/Example Title.md"})
+	}()
+	defer func() {
+		close(stop)
+		err := <-done
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	line, err := bufio.NewReader(pr).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(line, "previewing at http://127.0.0.1:") || !strings.HasSuffix(line, "/posts/mine/2000/01/01/Example%20Title.md\n") {
+		t.Fatalf("wrong output: %s", line)
+	}
+	pageURL := strings.TrimPrefix(strings.TrimSpace(line), "previewing at ")
+	root := pageURL[:strings.Index(pageURL, "/posts/")]
+
+	get := func(u string) (status int, body string) {
+		res, err := http.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, string(b)
+	}
+
+	status, page := get(pageURL)
+	if status != 200 || !strings.Contains(page, "<h1>Example Title</h1>") || !strings.Contains(page, `<h2 id="example-body">Example Body</h2>`) {
+		t.Errorf("wrong page: %d\n%s", status, page)
+	}
+
+	versionURL := root + "/versions/mine/2000/01/01/Example%20Title.md"
+	_, version := get(versionURL)
+	if !strings.Contains(page, version) {
+		t.Errorf("the page should have the version %s", version)
+	}
+	writePreviewPost(t, "## Example Edited Body")
+	_, edited := get(versionURL)
+	if edited == version {
+		t.Errorf("the version should change when the file is saved")
+	}
+
+	status, index := get(root + "/")
+	if status != 200 || !strings.Contains(index, `<a href="/posts/mine/2000/01/01/Example%20Title.md">Example Title</a>`) {
+		t.Errorf("wrong index: %d\n%s", status, index)
+	}
+
+	status, _ = get(root + "/posts/..%2f..%2fetc/passwd")
+	if status != 404 {
+		t.Errorf("files outside the workspace shouldn't be served: %d", status)
+	}
+
+	err = ioutil.WriteFile(".env", []byte("QIITA_ACCESS_TOKEN=XXXXXXXXXXXX\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(".env")
+	err = ioutil.WriteFile("mine/2000/01/01/image.png", []byte("png"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/2000/01/01/notes.txt", []byte("notes"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		"/posts/.env",
+		"/posts/.qiitactl/index.json",
+		"/posts/mine/2000/01/01/notes.txt",
+		"/versions/.env",
+	} {
+		status, _ = get(root + path)
+		if status != 404 {
+			t.Errorf("%s shouldn't be served: %d", path, status)
+		}
+	}
+	status, image := get(root + "/posts/mine/2000/01/01/image.png")
+	if status != 200 || image != "png" {
+		t.Errorf("images next to posts should be served: %d %s", status, image)
+	}
+}
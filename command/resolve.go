@@ -0,0 +1,47 @@
+package command
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type ResolveRunner struct {
+	Paths *[]string
+}
+
+// Resolve marks the conflicts in the files as resolved.
+// All the files with conflicts are checked when no file is specified.
+func (r ResolveRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	conflicts, err := model.LoadConflicts()
+	if err != nil {
+		return
+	}
+	paths := *r.Paths
+	if len(paths) == 0 {
+		paths = conflicts.Paths()
+	}
+	for _, path := range paths {
+		var post model.Post
+		post, err = model.NewPostWithFile(path)
+		if err != nil {
+			return
+		}
+		if post.HasConflictMarkers() {
+			err = fmt.Errorf("%s still has conflict markers", path)
+			return
+		}
+		delete(conflicts, post.Path)
+		err = conflicts.Save()
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "resolved %s\n", path)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type StatusRunner struct {
+	JSON *bool
+}
+
+// Status shows the local files of posts which differ from the posts in Qiita.
+func (r StatusRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	local, err := model.FetchLocalPosts()
+	if err != nil {
+		return
+	}
+	remote, err := fetchAllPosts(c, nil)
+	if err != nil {
+		return
+	}
+	statuses, err := model.Statuses(local, remote)
+	if err != nil {
+		return
+	}
+
+	if *r.JSON {
+		var b []byte
+		b, err = json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return
+	}
+	for _, status := range statuses {
+		_, err = fmt.Fprintf(w, "%-17s%s\n", strings.Replace(string(status.State), "_", " ", -1)+":", status.Path)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// teamScope is the set of the IDs of teams to be operated, with model.DirMine for the posts in Qiita.
+// All the teams are in the empty scope.
+type teamScope map[string]bool
+
+func newTeamScope(ids []string) (s teamScope) {
+	s = make(teamScope)
+	for _, id := range ids {
+		s[id] = true
+	}
+	return
+}
+
+func (s teamScope) has(id string) bool {
+	return len(s) == 0 || s[id]
+}
+
+// fetchAllPosts fetches your posts in Qiita and the teams in scope.
+func fetchAllPosts(c api.Client, scope teamScope) (posts model.Posts, err error) {
+	if scope.has(model.DirMine) {
+		posts, err = model.FetchPosts(c, nil)
+		if err != nil {
+			return
+		}
+	}
+	teams, err := model.FetchTeams(c)
+	if err != nil {
+		return
+	}
+	for _, team := range teams {
+		if !scope.has(team.ID) {
+			continue
+		}
+		var ps model.Posts
+		ps, err = model.FetchPosts(c, &team)
+		if err != nil {
+			return
+		}
+		posts = append(posts, ps...)
+	}
+	return
+}
@@ -0,0 +1,122 @@
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestStatus(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/authenticated_user/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Total-Count", "5")
+		w.Write([]byte(`[
+			{"id": "00000000000000000001", "title": "Clean", "body": "clean", "tags": [{"name": "Go"}], "updated_at": "2000-01-01T00:00:00+00:00"},
+			{"id": "00000000000000000002", "title": "Modified", "body": "remote", "tags": [{"name": "Go"}], "updated_at": "2000-01-01T00:00:00+00:00"},
+			{"id": "00000000000000000003", "title": "Remote Modified", "body": "remote", "tags": [{"name": "Go"}], "updated_at": "2000-01-02T00:00:00+00:00"},
+			{"id": "00000000000000000005", "title": "Duplicate", "body": "remote", "tags": [{"name": "Go"}], "updated_at": "2000-01-01T00:00:00+00:00"},
+			{"id": "00000000000000000006", "title": "Without Base", "body": "remote", "tags": [{"name": "Go"}], "updated_at": "2000-01-02T00:00:00+00:00"}
+		]`))
+	})
+	mux.HandleFunc("/api/v2/teams", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	files := map[string]string{
+		"clean.md":           "id: 00000000000000000001\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Clean\n\nclean",
+		"modified.md":        "id: 00000000000000000002\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Modified\n\nlocal",
+		"remote_modified.md": "id: 00000000000000000003\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Remote Modified\n\nremote",
+		"remote_deleted.md":  "id: 00000000000000000004\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Remote Deleted\n\nbody",
+		"duplicate-1.md":     "id: 00000000000000000005\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Duplicate\n\nremote",
+		"duplicate-2.md":     "id: 00000000000000000005\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Duplicate\n\nremote",
+		"new.md":             "id: \"\"\ndraft: true\n-->\n\n# New\n\nbody",
+		"without_base.md":    "id: 00000000000000000006\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Without Base\n\nlocal",
+	}
+	for name, content := range files {
+		path := filepath.Join("mine", name)
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte("<!--\ntags:\n- Go\n"+content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "status"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	expected := `duplicate:       mine/duplicate-1.md
+duplicate:       mine/duplicate-2.md
+modified:        mine/modified.md
+new:             mine/new.md
+remote deleted:  mine/remote_deleted.md
+remote modified: mine/remote_modified.md
+both modified:   mine/without_base.md
+`
+	if buf.String() != expected {
+		t.Errorf("wrong output:\n%s", testutil.Diff(expected, buf.String()))
+	}
+
+	err = ioutil.WriteFile(filepath.Join("mine", "remote_modified.md"), []byte("<!--\ntags:\n- Go\nid: 00000000000000000003\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Remote Modified\n\nlocal"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = model.Post{
+		Title: "Remote Modified",
+		Body:  "base",
+		Meta:  model.Meta{ID: "00000000000000000003"},
+	}.SaveBase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf = bytes.NewBuffer([]byte{})
+	app = command.New(inf, client, buf, errBuf)
+	app.Run([]string{"qiitactl", "status", "--json"})
+	if errBuf.Len() != 0 {
+		t.Fatal(errBuf.String())
+	}
+	var statuses []model.Status
+	err = json.Unmarshal(buf.Bytes(), &statuses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := make(map[string]model.State)
+	for _, status := range statuses {
+		states[status.Path] = status.State
+	}
+	if len(statuses) != 7 || states["mine/remote_modified.md"] != model.StateBothModified {
+		t.Errorf("wrong statuses: %s", buf.String())
+	}
+}
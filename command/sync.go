@@ -0,0 +1,217 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+// Actions taken by sync.
+const (
+	actionPulled        = "pulled"
+	actionPushed        = "pushed"
+	actionCreated       = "created"
+	actionConflict      = "conflict"
+	actionRemoteDeleted = "remote deleted"
+	actionDuplicate     = "duplicate"
+	actionFailed        = "failed"
+)
+
+var syncActions = []string{
+	actionPulled,
+	actionPushed,
+	actionCreated,
+	actionConflict,
+	actionRemoteDeleted,
+	actionDuplicate,
+	actionFailed,
+}
+
+type SyncRunner struct {
+	DryRun *bool
+	Teams  *[]string
+	AssetOptions
+}
+
+// Sync reconciles the local files of posts with Qiita and the teams.
+// It pulls the posts changed only in Qiita, pushes the posts changed only in local
+// and publishes the drafts whose publish_at has passed.
+// The posts changed on both sides and the files with unresolved conflicts are reported as conflicts and left untouched.
+// It goes on when some of the files fail, and reports them at the end.
+func (r SyncRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	scope := newTeamScope(*r.Teams)
+	remote, err := fetchAllPosts(c, scope)
+	if err != nil {
+		return
+	}
+	all, err := model.FetchLocalPosts()
+	if err != nil {
+		return
+	}
+	var local model.Posts
+	for _, post := range all {
+		if scope.has(post.TeamID()) {
+			local = append(local, post)
+		}
+	}
+	statuses, err := model.Statuses(local, remote)
+	if err != nil {
+		return
+	}
+
+	counts := make(map[string]int)
+	report := func(action, path string) (err error) {
+		counts[action]++
+		_, err = fmt.Fprintf(w, "%-15s%s\n", action, path)
+		return
+	}
+	fail := func(path string, e error) (err error) {
+		counts[actionFailed]++
+		msg := strings.SplitN(e.Error(), "\n", 2)[0]
+		_, err = fmt.Fprintf(w, "%-15s%s: %s\n", actionFailed, path, msg)
+		return
+	}
+
+	remotes := make(map[string]model.Post)
+	for _, post := range remote {
+		remotes[post.ID] = post
+	}
+	locals := make(map[string]model.Post)
+	for _, post := range local {
+		locals[post.ID] = post
+	}
+
+	var pulls, drafts model.Posts
+	for _, post := range remote {
+		if _, ok := locals[post.ID]; !ok {
+			pulls = append(pulls, post)
+		}
+	}
+	for _, status := range statuses {
+		switch status.State {
+		case model.StateRemoteModified:
+			pulls = append(pulls, remotes[status.ID])
+		case model.StateModified:
+			err = r.push(c, o.Error, status.Path, report, fail)
+		case model.StateNew:
+			post, e := model.NewPostWithFile(status.Path)
+			if e != nil {
+				err = fail(status.Path, e)
+				break
+			}
+			drafts = append(drafts, post)
+		case model.StateBothModified:
+			err = report(actionConflict, status.Path)
+		case model.StateRemoteDeleted:
+			err = report(actionRemoteDeleted, status.Path)
+		case model.StateDuplicate:
+			err = report(actionDuplicate, status.Path)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if *r.DryRun {
+		for _, post := range pulls {
+			// The posts only in Qiita have no files yet.
+			path := post.ID
+			if l, ok := locals[post.ID]; ok {
+				path = l.Path
+			}
+			err = report(actionPulled, path)
+			if err != nil {
+				return
+			}
+		}
+		for _, post := range drafts.Due(time.Now()) {
+			err = report(actionCreated, post.Path)
+			if err != nil {
+				return
+			}
+		}
+	} else {
+		var conflicts []string
+		conflicts, err = pulls.SaveMerged()
+		if err != nil {
+			return
+		}
+		conflicted := make(map[string]bool)
+		for _, path := range conflicts {
+			conflicted[path] = true
+			err = report(actionConflict, path)
+			if err != nil {
+				return
+			}
+		}
+		for _, post := range pulls {
+			if conflicted[post.Path] {
+				continue
+			}
+			err = report(actionPulled, post.Path)
+			if err != nil {
+				return
+			}
+		}
+		err = publishDrafts(c, o.Error, drafts.Due(time.Now()), model.CreationOptions{}, r.AssetOptions, func(post model.Post, err error) error {
+			if err != nil {
+				return fail(post.Path, err)
+			}
+			return report(actionCreated, post.Path)
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = fmt.Fprintln(w)
+	if err != nil {
+		return
+	}
+	for _, action := range syncActions {
+		_, err = fmt.Fprintf(w, "%-15s%d\n", action, counts[action])
+		if err != nil {
+			return
+		}
+	}
+	if counts[actionFailed] > 0 {
+		err = fmt.Errorf("sync: %d files failed", counts[actionFailed])
+	}
+	return
+}
+
+// push updates the post with the modified file at path unless conflicts are left in the file
+// or the post is updated in Qiita after it is fetched, which are reported as conflicts.
+// The drafts are skipped even if they have IDs.
+// The other errors are reported as failures.
+func (r SyncRunner) push(c api.Client, ew io.Writer, path string, report func(action, path string) error, fail func(path string, err error) error) (err error) {
+	post, err := model.NewPostWithFile(path)
+	if err == nil && post.Draft {
+		return
+	}
+	if err == nil {
+		err = post.CheckResolved()
+	}
+	if err == nil && !*r.DryRun {
+		_, err = post.CheckConflict(c)
+	}
+	switch err.(type) {
+	case nil:
+	case model.UnresolvedError, model.ConflictError:
+		return report(actionConflict, path)
+	default:
+		return fail(path, err)
+	}
+	if *r.DryRun {
+		return report(actionPushed, path)
+	}
+	err = updatePost(c, ew, &post, r.AssetOptions)
+	if err != nil {
+		return fail(path, err)
+	}
+	return report(actionPushed, path)
+}
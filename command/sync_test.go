@@ -0,0 +1,212 @@
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestSync(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	requests := 0
+	mux := http.NewServeMux()
+	handleItems(mux)
+	mux.HandleFunc("/api/v2/authenticated_user/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Total-Count", "8")
+		w.Write([]byte(`[
+			{"id": "00000000000000000001", "title": "Remote Modified", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-02T00:00:00+00:00"},
+			{"id": "00000000000000000002", "title": "Modified", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-01T00:00:00+00:00"},
+			{"id": "00000000000000000003", "title": "Only Remote", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-03T00:00:00+00:00", "updated_at": "2000-01-03T00:00:00+00:00"},
+			{"id": "00000000000000000004", "title": "Both", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-02T00:00:00+00:00"},
+			{"id": "00000000000000000005", "title": "Unresolved", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-01T00:00:00+00:00"},
+			{"id": "00000000000000000006", "title": "Failed", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-01T00:00:00+00:00"},
+			{"id": "00000000000000000007", "title": "Without Base", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-02T00:00:00+00:00"},
+			{"id": "00000000000000000008", "title": "Unpublished", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-01T00:00:00+00:00"}
+		]`))
+	})
+	mux.HandleFunc("/api/v2/teams", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+	mux.HandleFunc("/api/v2/items/00000000000000000006", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`{"id": "00000000000000000006", "title": "Failed", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-01T00:00:00+00:00"}`))
+			return
+		}
+		testutil.ResponseError(w, 500, fmt.Errorf("server error"))
+	})
+	mux.HandleFunc("/api/v2/items/00000000000000000002", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`{"id": "00000000000000000002", "title": "Modified", "body": "remote", "tags": [{"name": "Go"}], "created_at": "2000-01-01T00:00:00+00:00", "updated_at": "2000-01-01T00:00:00+00:00"}`))
+			return
+		}
+		var post model.Post
+		err := json.NewDecoder(r.Body).Decode(&post)
+		if err != nil {
+			testutil.ResponseError(w, 500, err)
+			return
+		}
+		post.UpdatedAt = model.Time{Time: post.UpdatedAt.AddDate(0, 0, 1)}
+		b, _ := json.Marshal(post)
+		w.Write(b)
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			requests++
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	files := map[string]string{
+		"remote_modified.md": "id: 00000000000000000001\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Remote Modified\n\nlocal",
+		"modified.md":        "id: 00000000000000000002\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Modified\n\nlocal",
+		"both.md":            "id: 00000000000000000004\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Both\n\nlocal",
+		"unresolved.md":      "id: 00000000000000000005\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Unresolved\n\n<<<<<<< local\nlocal\n=======\nremote\n>>>>>>> remote",
+		"failed.md":          "id: 00000000000000000006\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Failed\n\nlocal",
+		"without_base.md":    "id: 00000000000000000007\nupdated_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Without Base\n\nlocal",
+		"unpublished.md":     "id: 00000000000000000008\nupdated_at: 2000-01-01T09:00:00+09:00\ndraft: true\n-->\n\n# Unpublished\n\nlocal",
+		"draft.md":           "draft: true\npublish_at: 2000-01-01T09:00:00+09:00\n-->\n\n# Draft\n\ndraft",
+	}
+	for name, content := range files {
+		path := filepath.Join("mine", name)
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte("<!--\ntags:\n- Go\n"+content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, base := range []model.Post{
+		{Title: "Remote Modified", Body: "local", Meta: model.Meta{ID: "00000000000000000001", Tags: model.Tags{{Name: "Go"}}}},
+		{Title: "Both", Body: "base", Meta: model.Meta{ID: "00000000000000000004", Tags: model.Tags{{Name: "Go"}}}},
+		{Title: "Unresolved", Body: "remote", Meta: model.Meta{ID: "00000000000000000005", Tags: model.Tags{{Name: "Go"}}}},
+		{Title: "Failed", Body: "remote", Meta: model.Meta{ID: "00000000000000000006", Tags: model.Tags{{Name: "Go"}}}},
+		{Title: "Unpublished", Body: "remote", Meta: model.Meta{ID: "00000000000000000008", Tags: model.Tags{{Name: "Go"}}}},
+	} {
+		err = base.SaveBase()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) string {
+		buf := bytes.NewBuffer([]byte{})
+		errBuf := bytes.NewBuffer([]byte{})
+		app := command.New(inf, client, buf, errBuf)
+		app.Run(append([]string{"qiitactl", "sync"}, args...))
+		return buf.String() + errBuf.String()
+	}
+
+	actual := run("--dry-run")
+	expected := `conflict       mine/both.md
+pushed         mine/failed.md
+pushed         mine/modified.md
+conflict       mine/unresolved.md
+conflict       mine/without_base.md
+pulled         00000000000000000003
+pulled         mine/remote_modified.md
+created        mine/draft.md
+
+pulled         2
+pushed         2
+created        1
+conflict       3
+remote deleted 0
+duplicate      0
+failed         0
+`
+	if actual != expected {
+		t.Errorf("wrong output:\n%s", testutil.Diff(expected, actual))
+	}
+	if requests != 0 {
+		t.Fatalf("nothing should be sent with --dry-run: %d", requests)
+	}
+	testutil.ShouldExistFile(t, 8)
+
+	actual = run()
+	expected = `conflict       mine/both.md
+failed         mine/failed.md: server error
+pushed         mine/modified.md
+conflict       mine/unresolved.md
+conflict       mine/without_base.md
+pulled         mine/2000/01/03/Only Remote.md
+pulled         mine/remote_modified.md
+created        mine/draft.md
+
+pulled         2
+pushed         1
+created        1
+conflict       3
+remote deleted 0
+duplicate      0
+failed         1
+sync: 1 files failed
+`
+	if actual != expected {
+		t.Errorf("wrong output:\n%s", testutil.Diff(expected, actual))
+	}
+	if requests != 3 {
+		t.Errorf("wrong number of requests: %d", requests)
+	}
+	testutil.ShouldExistFile(t, 9)
+
+	post, err := model.NewPostWithFile("mine/remote_modified.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Body != "remote" {
+		t.Errorf("remote change should be pulled: %s", post.Body)
+	}
+	post, err = model.NewPostWithFile("mine/both.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Body != "local" {
+		t.Errorf("conflicted file shouldn't be changed: %s", post.Body)
+	}
+	post, err = model.NewPostWithFile("mine/without_base.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Body != "local" {
+		t.Errorf("file changed on both sides without the snapshot shouldn't be changed: %s", post.Body)
+	}
+	post, err = model.NewPostWithFile("mine/unpublished.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !post.Draft || post.Body != "local" {
+		t.Errorf("draft with ID shouldn't be pushed: %+v", post)
+	}
+	post, err = model.NewPostWithFile("mine/draft.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.ID == "" || post.Draft {
+		t.Errorf("draft should be published: %+v", post)
+	}
+}
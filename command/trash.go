@@ -0,0 +1,115 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+// confirm asks the question and reports whether it is answered with yes.
+func confirm(in io.Reader, w io.Writer, question string) (yes bool, err error) {
+	_, err = fmt.Fprintf(w, "%s [y/N]: ", question)
+	if err != nil {
+		return
+	}
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return
+	}
+	err = nil
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		yes = true
+	}
+	return
+}
+
+type RestoreRunner struct {
+	ID *string
+	AssetOptions
+}
+
+// Restore creates the post in the trash again as a new post,
+// and moves the file back to the workspace.
+func (r RestoreRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	t, err := model.LoadTrash(*r.ID)
+	if err != nil {
+		return
+	}
+	post, err := t.Post()
+	if err != nil {
+		return
+	}
+	err = createPost(c, o.Error, &post, model.CreationOptions{}, r.AssetOptions)
+	if err != nil {
+		return
+	}
+	err = t.Purge()
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "restored %s as %s\n", post.Path, post.ID)
+	return
+}
+
+type ShowTrashRunner struct{}
+
+// ShowTrash prints the posts in the trash.
+func (r ShowTrashRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	trashes, err := model.LoadTrashes()
+	if err != nil {
+		return
+	}
+	for _, t := range trashes {
+		_, err = fmt.Fprintf(w, "%s %s %s %s\n", t.ID, t.DeletedAt.Local().Format(time.RFC3339), t.Path, t.Title)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+type PurgeRunner struct {
+	IDs *[]string
+	All *bool
+}
+
+// Purge removes the posts from the trash permanently.
+func (r PurgeRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	var trashes []model.Trash
+	if *r.All {
+		trashes, err = model.LoadTrashes()
+		if err != nil {
+			return
+		}
+	} else {
+		if len(*r.IDs) == 0 {
+			err = fmt.Errorf("purge: ids or --all is required")
+			return
+		}
+		for _, id := range *r.IDs {
+			var t model.Trash
+			t, err = model.LoadTrash(id)
+			if err != nil {
+				return
+			}
+			trashes = append(trashes, t)
+		}
+	}
+	for _, t := range trashes {
+		err = t.Purge()
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "purged %s\n", t.ID)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
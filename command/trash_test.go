@@ -0,0 +1,109 @@
+package command_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestTrash(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	handleItems(mux)
+	handleItem(mux)
+	serverMine := httptest.NewServer(mux)
+	defer serverMine.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		switch subDomain {
+		case "":
+			url = fmt.Sprintf("%s%s%s", serverMine.URL, "/api/v2", path)
+		default:
+			log.Fatalf("wrong sub domain \"%s\"", subDomain)
+		}
+		return
+	}, inf)
+
+	post := model.NewPost("Example Title", nil, nil)
+	post.ID = "4bd431809afb1bb99e4f"
+	post.Tags = model.Tags{{Name: "Go"}}
+	post.Body = "## Example Body"
+	err = post.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := post.Path
+
+	run := func(args ...string) string {
+		buf := bytes.NewBuffer([]byte{})
+		errBuf := bytes.NewBuffer([]byte{})
+		app := command.New(inf, client, buf, errBuf)
+		app.Run(append([]string{"qiitactl"}, args...))
+		e := errBuf.Bytes()
+		if len(e) != 0 {
+			t.Fatal(string(e))
+		}
+		return buf.String()
+	}
+
+	run("delete", "post", "--yes", path)
+	testutil.ShouldExistFile(t, 0)
+
+	out := run("show", "trash")
+	if !strings.HasPrefix(out, "4bd431809afb1bb99e4f ") || !strings.HasSuffix(out, fmt.Sprintf(" %s Example Title\n", path)) {
+		t.Errorf("wrong output: %s", out)
+	}
+
+	out = run("restore", "4bd431809afb1bb99e4f")
+	if out != fmt.Sprintf("restored %s as 4bd431809afb1bb99e4f\n", path) {
+		t.Errorf("wrong output: %s", out)
+	}
+	testutil.ShouldExistFile(t, 1)
+	restored, err := model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Title != "Example Title" || restored.Body != "## Example Body" {
+		t.Errorf("wrong post: %+v", restored)
+	}
+	if out := run("show", "trash"); out != "" {
+		t.Errorf("trash should be empty: %s", out)
+	}
+
+	run("delete", "post", "--yes", path)
+	out = run("purge", "--all")
+	if out != "purged 4bd431809afb1bb99e4f\n" {
+		t.Errorf("wrong output: %s", out)
+	}
+	_, err = model.LoadTrash("4bd431809afb1bb99e4f")
+	if err == nil {
+		t.Errorf("trash should be purged")
+	}
+}
+
+func TestPurgeWithoutIDs(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, api.NewClient(nil, inf), os.Stdout, errBuf)
+	err := app.Run([]string{"qiitactl", "purge"})
+	if err == nil {
+		t.Fatal("error should occur")
+	}
+}
@@ -0,0 +1,194 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+type WatchRunner struct {
+	Paths         *[]string
+	Interval      *time.Duration
+	Debounce      *time.Duration
+	DraftsPreview *bool
+	Force         *bool
+	AssetOptions
+
+	// Stop stops watching when it is closed.
+	// It is nil in the command line, so that watch keeps running.
+	Stop <-chan struct{}
+}
+
+// Watch keeps running and updates the posts in Qiita when their files are saved.
+// The files are polled every interval, and a file is updated once it hasn't been saved for the debounce duration.
+// With --drafts-preview, the drafts are pushed to private items to preview them instead.
+func (r WatchRunner) Run(c api.Client, o GlobalOptions, w io.Writer) (err error) {
+	seen, err := r.scan()
+	if err != nil {
+		return
+	}
+	pending := make(map[string]time.Time)
+	ticker := time.NewTicker(*r.Interval)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-r.Stop:
+			return
+		case now = <-ticker.C:
+		}
+
+		var entries map[string]model.IndexEntry
+		entries, err = r.scan()
+		if err != nil {
+			return
+		}
+		for path, entry := range entries {
+			if entry.Hash != seen[path].Hash {
+				pending[path] = now
+			}
+		}
+		seen = entries
+
+		var ready []string
+		for path, savedAt := range pending {
+			if _, ok := seen[path]; !ok {
+				delete(pending, path)
+				continue
+			}
+			if now.Sub(savedAt) >= *r.Debounce {
+				ready = append(ready, path)
+				delete(pending, path)
+			}
+		}
+		sort.Strings(ready)
+		var updated []string
+		for _, path := range ready {
+			action, url, e := r.push(c, o.Error, path)
+			if e == nil && action == "updated" {
+				updated = append(updated, path)
+			}
+			if e != nil {
+				msg := strings.SplitN(e.Error(), "\n", 2)[0]
+				_, err = fmt.Fprintf(w, "%-10s%s: %s\n", "failed", path, msg)
+			} else {
+				_, err = fmt.Fprintf(w, "%-10s%s %s\n", action, path, url)
+			}
+			if err != nil {
+				return
+			}
+		}
+
+		if len(updated) == 0 {
+			continue
+		}
+		// The updated files are rewritten with the new updated_at,
+		// which must not be taken as another save.
+		entries, err = r.scan()
+		if err != nil {
+			return
+		}
+		for _, path := range updated {
+			seen[path] = entries[path]
+		}
+	}
+}
+
+// scan returns the index entries of the watched files with the paths as keys.
+func (r WatchRunner) scan() (entries map[string]model.IndexEntry, err error) {
+	index, err := model.UpdateIndex()
+	if err != nil {
+		return
+	}
+	entries = make(map[string]model.IndexEntry)
+	for path, entry := range index {
+		if entry.Invalid || !inPaths(path, *r.Paths) {
+			continue
+		}
+		if entry.Draft {
+			if !*r.DraftsPreview {
+				continue
+			}
+		} else if entry.ID == "" {
+			// New posts aren't created by watch.
+			continue
+		}
+		entries[path] = entry
+	}
+	return
+}
+
+// inPaths reports whether path is one of paths or in one of them.
+// Any path is in empty paths.
+func inPaths(path string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if p == "." || path == p || strings.HasPrefix(path, p+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// push validates the file at path and updates the post in Qiita, or previews the draft.
+// url is the URL of the updated post or the private item of the preview.
+func (r WatchRunner) push(c api.Client, ew io.Writer, path string) (action, url string, err error) {
+	post, err := model.NewPostWithFile(path)
+	if err != nil {
+		return
+	}
+	if e := post.Validate(); e != nil {
+		err = e
+		return
+	}
+	err = post.CheckResolved()
+	if err != nil {
+		return
+	}
+	if post.Draft {
+		action = "previewed"
+		url, err = r.preview(c, post)
+		return
+	}
+
+	action = "updated"
+	if !*r.Force {
+		_, err = post.CheckConflict(c)
+		if err != nil {
+			return
+		}
+	}
+	err = updatePost(c, ew, &post, r.AssetOptions)
+	url = post.URL
+	return
+}
+
+// preview pushes the draft to the private item recorded for it, or to a new one.
+func (r WatchRunner) preview(c api.Client, post model.Post) (url string, err error) {
+	previews, err := model.LoadPreviews()
+	if err != nil {
+		return
+	}
+	post.Body, err = r.processAssets(post)
+	if err != nil {
+		return
+	}
+	preview, err := post.Preview(c, previews[post.Path])
+	if err != nil {
+		return
+	}
+	previews[post.Path] = preview.ID
+	err = previews.Save()
+	url = preview.URL
+	return
+}
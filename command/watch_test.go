@@ -0,0 +1,177 @@
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/command"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestWatch(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	patched := make(chan string, 10)
+	posted := make(chan model.Post, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/v2/items/")
+		var post model.Post
+		switch r.Method {
+		case "GET":
+			fmt.Fprintf(w, `{"id": %q, "updated_at": "2000-01-01T00:00:00+00:00"}`, id)
+			return
+		case "PATCH":
+			patched <- id
+		case "POST":
+			id = "ffffffffffffffffffff"
+		}
+		err := json.NewDecoder(r.Body).Decode(&post)
+		if err != nil {
+			testutil.ResponseError(w, 500, err)
+			return
+		}
+		if r.Method == "POST" {
+			posted <- post
+		}
+		post.ID = id
+		post.URL = "https://qiita.com/yaotti/items/" + id
+		b, _ := json.Marshal(post)
+		w.Write(b)
+	}))
+	defer server.Close()
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	write := func(path, meta, body string) {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(fmt.Sprintf("<!--\n%stags:\n- Go\n-->\n\n# Title\n\n%s", meta, body)), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	published := func(id string) string {
+		return fmt.Sprintf("id: %s\nupdated_at: 2000-01-01T09:00:00+09:00\n", id)
+	}
+	write("mine/1.md", published("00000000000000000001"), "body")
+	write("mine/2.md", published("00000000000000000002"), "body")
+
+	buf := bytes.NewBuffer([]byte{})
+	errBuf := bytes.NewBuffer([]byte{})
+	app := command.New(inf, client, buf, errBuf)
+	stop := make(chan struct{})
+	app.WatchRunner.Stop = stop
+	done := make(chan error)
+	go func() {
+		done <- app.Run([]string{"qiitactl", "watch", "--interval", "10ms", "--debounce", "100ms", "--drafts-preview"})
+	}()
+	stopped := false
+	defer func() {
+		if !stopped {
+			close(stop)
+			<-done
+		}
+	}()
+
+	// Wait for the first scan, which doesn't take the existing files as saved.
+	for i := 0; ; i++ {
+		if _, err := os.Stat(model.IndexPath); err == nil {
+			break
+		}
+		if i == 500 {
+			t.Fatal("watch doesn't start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, body := range []string{"edited", "edited again", "edited once more"} {
+		write("mine/1.md", published("00000000000000000001"), body)
+		time.Sleep(5 * time.Millisecond)
+	}
+	write("mine/draft.md", "draft: true\n", "draft")
+	write("mine/new.md", "", "new")
+
+	select {
+	case id := <-patched:
+		if id != "00000000000000000001" {
+			t.Errorf("wrong patched post: %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the post isn't updated")
+	}
+	select {
+	case post := <-posted:
+		if !post.Private || post.Body != "draft" {
+			t.Errorf("wrong preview: %+v", post)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the draft isn't previewed")
+	}
+
+	write("mine/draft.md", "draft: true\n", "draft edited")
+	select {
+	case id := <-patched:
+		if id != "ffffffffffffffffffff" {
+			t.Errorf("wrong patched post: %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the preview isn't updated")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	close(stop)
+	stopped = true
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id := <-patched:
+		t.Errorf("the post shouldn't be updated again: %s", id)
+	default:
+	}
+	select {
+	case post := <-posted:
+		t.Errorf("the new post which isn't a draft shouldn't be sent: %+v", post)
+	default:
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		"updated   mine/1.md https://qiita.com/yaotti/items/00000000000000000001",
+		"previewed mine/draft.md https://qiita.com/yaotti/items/ffffffffffffffffffff",
+		"previewed mine/draft.md https://qiita.com/yaotti/items/ffffffffffffffffffff",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong output:\n%s", testutil.Diff(strings.Join(expected, "\n"), strings.Join(lines, "\n")))
+	}
+
+	draft, err := model.NewPostWithFile("mine/draft.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !draft.Draft || draft.ID != "" {
+		t.Errorf("the draft shouldn't be published: %+v", draft)
+	}
+}
@@ -0,0 +1,163 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Formats of the diagnostics.
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// Write writes the diagnostics in format.
+func Write(w io.Writer, format string, diags []Diagnostic) (err error) {
+	switch format {
+	case FormatText, "":
+		err = WriteText(w, diags)
+	case FormatJSON:
+		err = WriteJSON(w, diags)
+	case FormatSARIF:
+		err = WriteSARIF(w, diags)
+	default:
+		err = fmt.Errorf("lint: unknown format %s", format)
+	}
+	return
+}
+
+// WriteText writes a diagnostic per line as `path:line:column: severity: message (rule)`.
+func WriteText(w io.Writer, diags []Diagnostic) (err error) {
+	for _, d := range diags {
+		_, err = fmt.Fprintln(w, d)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// WriteJSON writes the diagnostics as a JSON array.
+func WriteJSON(w io.Writer, diags []Diagnostic) (err error) {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	b, err := json.MarshalIndent(diags, "", "  ")
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// WriteSARIF writes the diagnostics in SARIF 2.1.0 to be read by code scanning tools.
+func WriteSARIF(w io.Writer, diags []Diagnostic) (err error) {
+	driver := sarifDriver{
+		Name:           "qiitactl",
+		InformationURI: "https://github.com/minodisk/qiitactl",
+		Rules: []sarifRule{
+			{
+				ID:               RuleFormat,
+				ShortDescription: sarifMessage{Text: "Files must be parsed as posts."},
+			},
+		},
+	}
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               rule.Name(),
+			ShortDescription: sarifMessage{Text: rule.Description()},
+		})
+	}
+	results := []sarifResult{}
+	for _, d := range diags {
+		results = append(results, sarifResult{
+			RuleID:  d.Rule,
+			Level:   string(d.Severity),
+			Message: sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.Path)},
+						Region: sarifRegion{
+							StartLine:   d.Line,
+							StartColumn: d.Column,
+						},
+					},
+				},
+			},
+		})
+	}
+	b, err := json.MarshalIndent(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{
+			{
+				Tool:    sarifTool{Driver: driver},
+				Results: results,
+			},
+		},
+	}, "", "  ")
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return
+}
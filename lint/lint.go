@@ -0,0 +1,360 @@
+// Package lint checks the files of posts with rules before they are sent to Qiita.
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/minodisk/qiitactl/model"
+)
+
+// Severity is how serious a problem is.
+// The problems of error block creating and updating posts.
+type Severity string
+
+// Severities of rules.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityOff     Severity = "off"
+)
+
+// Diagnostic is a problem found in a file.
+type Diagnostic struct {
+	Path     string   `json:"path"`     // ファイルのパス
+	Line     int      `json:"line"`     // 1から始まる行
+	Column   int      `json:"column"`   // 1から始まる列
+	Rule     string   `json:"rule"`     // ルールの名前
+	Severity Severity `json:"severity"` // 問題の深刻さ
+	Message  string   `json:"message"`  // 問題の説明
+	Fixable  bool     `json:"fixable"`  // --fixで直せるかどうか
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", d.Path, d.Line, d.Column, d.Severity, d.Message, d.Rule)
+}
+
+// Rule checks a file of a post.
+type Rule interface {
+	// Name returns the name of the rule used in the configuration and the diagnostics.
+	Name() string
+	// Description returns the short description of the rule.
+	Description() string
+	// Severity returns the severity of the rule when it isn't configured.
+	Severity() Severity
+	// Check returns the problems in the file with their positions and messages.
+	Check(f File, c model.LintRule) []Diagnostic
+}
+
+// Fixer is a rule which can fix the problems it finds.
+type Fixer interface {
+	Rule
+	// Fix fixes the problems in the post.
+	Fix(post *model.Post, c model.LintRule)
+}
+
+var rules []Rule
+
+// Register adds a rule to the rules run by lint.
+// The rules run in the order they are registered.
+func Register(rule Rule) {
+	rules = append(rules, rule)
+}
+
+// Rules returns the registered rules.
+func Rules() []Rule {
+	return rules
+}
+
+// File is a file of a post to be checked.
+type File struct {
+	Post     model.Post
+	Lines    []string // ファイルの行
+	BodyLine int      // 本文が始まる行
+}
+
+// NewFile reads and parses the file at path.
+func NewFile(path string) (f File, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	f, err = Parse(path, b)
+	return
+}
+
+// Parse parses the content b of the file at path, such as a buffer in an editor.
+func Parse(path string, b []byte) (f File, err error) {
+	err = f.Post.Decode(b)
+	if err != nil {
+		return
+	}
+	f.Post.Path = path
+	content := string(b)
+	f.Lines = strings.Split(content, "\n")
+	f.BodyLine = len(f.Lines) + 1
+	if f.Post.Body != "" {
+		if i := strings.LastIndex(content, f.Post.Body); i >= 0 {
+			f.BodyLine = strings.Count(content[:i], "\n") + 1
+		}
+	}
+	return
+}
+
+// RuleFormat is the name of the rule reported for the files which can't be parsed as posts.
+const RuleFormat = "format"
+
+// Unparsable returns the diagnostic of the file at path which can't be parsed as a post.
+func Unparsable(path string, err error) Diagnostic {
+	return Diagnostic{
+		Path:     path,
+		Line:     1,
+		Column:   1,
+		Rule:     RuleFormat,
+		Severity: SeverityError,
+		Message:  err.Error(),
+	}
+}
+
+// FieldLine returns the line of the field of the post with name, such as title, body or a key in the meta.
+func (f File) FieldLine(name string) int {
+	switch name {
+	case "title":
+		return f.titleLine()
+	case "body":
+		if f.BodyLine > len(f.Lines) {
+			return f.titleLine()
+		}
+		return f.BodyLine
+	}
+	return f.metaLine(name)
+}
+
+// metaLine returns the line of key in the header of the file.
+func (f File) metaLine(key string) int {
+	for i := 0; i < len(f.Lines) && i+1 < f.BodyLine; i++ {
+		if strings.HasPrefix(strings.TrimSpace(f.Lines[i]), key+":") {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+// titleLine returns the line of the title in the file.
+func (f File) titleLine() int {
+	if f.Post.Format == model.FormatFrontMatter {
+		return f.metaLine("title")
+	}
+	for i := 0; i < len(f.Lines) && i+1 < f.BodyLine; i++ {
+		if strings.HasPrefix(f.Lines[i], "# ") {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+var rFence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+// bodyLine is a line of the body.
+type bodyLine struct {
+	Number int
+	Text   string
+	Code   bool // コードブロックの中の行かどうか
+}
+
+// scanBody splits body into the lines numbered from start.
+// unclosed is the line of the fence which opens a code block never closed, or 0.
+func scanBody(body string, start int) (lines []bodyLine, unclosed int) {
+	fence := ""
+	for i, text := range strings.Split(body, "\n") {
+		line := bodyLine{Number: start + i, Text: text}
+		m := rFence.FindStringSubmatch(text)
+		switch {
+		case fence == "" && m != nil:
+			fence = m[1]
+			unclosed = line.Number
+			line.Code = true
+		case fence != "":
+			line.Code = true
+			trimmed := strings.TrimSpace(text)
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+				unclosed = 0
+			}
+		}
+		lines = append(lines, line)
+	}
+	return
+}
+
+func (f File) bodyLines() (lines []bodyLine, unclosed int) {
+	return scanBody(f.Post.Body, f.BodyLine)
+}
+
+var rCodeSpan = regexp.MustCompile("`+[^`]*`+")
+
+// maskCode replaces the code spans in text with spaces to keep the columns.
+func maskCode(text string) string {
+	return rCodeSpan.ReplaceAllStringFunc(text, func(s string) string {
+		return strings.Repeat(" ", len(s))
+	})
+}
+
+// column returns the column of the byte offset i in text counted in characters.
+func column(text string, i int) int {
+	return utf8.RuneCountInString(text[:i]) + 1
+}
+
+func severityOf(rule Rule, c model.LintRule) (severity Severity, err error) {
+	switch Severity(c.Severity) {
+	case "":
+		severity = rule.Severity()
+	case SeverityError, SeverityWarning, SeverityOff:
+		severity = Severity(c.Severity)
+	default:
+		err = fmt.Errorf("lint: unknown severity %s of %s", c.Severity, rule.Name())
+	}
+	return
+}
+
+func validateConfig(config map[string]model.LintRule) (err error) {
+	for name := range config {
+		found := false
+		for _, rule := range rules {
+			if rule.Name() == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			err = fmt.Errorf("lint: unknown rule %s", name)
+			return
+		}
+	}
+	return
+}
+
+// Lint checks the file with the rules configured with config,
+// and returns the problems sorted by their positions.
+func Lint(f File, config map[string]model.LintRule) (diags []Diagnostic, err error) {
+	err = validateConfig(config)
+	if err != nil {
+		return
+	}
+	diags = []Diagnostic{}
+	for _, rule := range rules {
+		c := config[rule.Name()]
+		var severity Severity
+		severity, err = severityOf(rule, c)
+		if err != nil {
+			return
+		}
+		if severity == SeverityOff {
+			continue
+		}
+		_, fixable := rule.(Fixer)
+		for _, d := range rule.Check(f, c) {
+			d.Path = f.Post.Path
+			d.Rule = rule.Name()
+			d.Severity = severity
+			d.Fixable = fixable
+			diags = append(diags, d)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
+	return
+}
+
+// Fix fixes the problems found by the fixable rules configured with config.
+// fixed reports whether the post is changed.
+func Fix(f File, config map[string]model.LintRule) (post model.Post, fixed bool, err error) {
+	err = validateConfig(config)
+	if err != nil {
+		return
+	}
+	post = f.Post
+	for _, rule := range rules {
+		fixer, ok := rule.(Fixer)
+		if !ok {
+			continue
+		}
+		c := config[rule.Name()]
+		var severity Severity
+		severity, err = severityOf(rule, c)
+		if err != nil {
+			return
+		}
+		if severity == SeverityOff {
+			continue
+		}
+		f.Post = post
+		if len(rule.Check(f, c)) == 0 {
+			continue
+		}
+		fixer.Fix(&post, c)
+		fixed = true
+	}
+	return
+}
+
+// Blocking checks the file of the post with the rules whose severity is error,
+// and returns Error when any problem is found.
+// The post is checked as it is encoded when the file doesn't exist, like a post restored from the trash.
+func Blocking(post model.Post) (err error) {
+	config, err := model.LoadConfig()
+	if err != nil {
+		return
+	}
+	b, err := ioutil.ReadFile(post.Path)
+	if os.IsNotExist(err) {
+		var buf bytes.Buffer
+		err = post.Encode(&buf)
+		b = buf.Bytes()
+	}
+	if err != nil {
+		return
+	}
+	f, err := Parse(post.Path, b)
+	if err != nil {
+		return
+	}
+	diags, err := Lint(f, config.Lint)
+	if err != nil {
+		return
+	}
+	var errs []Diagnostic
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	if len(errs) > 0 {
+		err = Error{Diagnostics: errs}
+	}
+	return
+}
+
+// Error occurs when a post has problems which block sending it to Qiita.
+type Error struct {
+	Diagnostics []Diagnostic
+}
+
+func (err Error) Error() (msg string) {
+	lines := []string{"lint: the problems must be fixed before sending the post to Qiita:"}
+	for _, d := range err.Diagnostics {
+		lines = append(lines, d.String())
+	}
+	msg = strings.Join(lines, "\n")
+	return
+}
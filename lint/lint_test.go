@@ -0,0 +1,165 @@
+package lint_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/lint"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func newFile(t *testing.T, content string) lint.File {
+	err := os.MkdirAll("mine", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("mine/lint.md", []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := lint.NewFile("mine/lint.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestLint(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	f := newFile(t, "<!--\n"+
+		"id: 4bd431809afb1bb99e4f\n"+
+		"tags:\n"+
+		"- Go\n"+
+		"- go\n"+
+		"- Bad Tag\n"+
+		"-->\n"+
+		"\n"+
+		"# Title\n"+
+		"\n"+
+		"#### Skipped\n"+
+		"\n"+
+		"![](image.png) `https://example.com/code` see https://example.com.\n"+
+		"[link](https://example.com) <https://example.com>\n"+
+		"\n"+
+		"```go\n"+
+		"// https://example.com\n")
+	diags, err := lint.Lint(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, d := range diags {
+		actual = append(actual, d.String())
+	}
+	expected := []string{
+		`mine/lint.md:3:1: error: the tag "Bad Tag" has invalid characters (tag-chars)`,
+		`mine/lint.md:3:1: error: the tag "go" is duplicated (duplicate-tags)`,
+		"mine/lint.md:11:1: warning: the heading of level 4 follows the one of level 1 (heading-level)",
+		"mine/lint.md:13:1: warning: the image has no alternative text (image-alt)",
+		"mine/lint.md:13:47: warning: the URL https://example.com isn't written as a link (bare-url)",
+		"mine/lint.md:16:1: error: the code block isn't closed (unclosed-code-fence)",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong diagnostics:\n%s", testutil.Diff(strings.Join(expected, "\n"), strings.Join(actual, "\n")))
+	}
+}
+
+func TestLintWithConfig(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	f := newFile(t, "<!--\ntags:\n- Go\n-->\n\n# Long Title\n\n#### Skipped")
+	diags, err := lint.Lint(f, map[string]model.LintRule{
+		"tag-count":     {Min: 2},
+		"title-length":  {Max: 5, Severity: "warning"},
+		"heading-level": {Severity: "off"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, d := range diags {
+		actual = append(actual, d.String())
+	}
+	expected := []string{
+		"mine/lint.md:2:1: error: 1 tags are given, but 2 to 5 tags are required (tag-count)",
+		"mine/lint.md:6:1: warning: the title has 10 characters, but 1 to 5 characters are required (title-length)",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong diagnostics:\n%s", testutil.Diff(strings.Join(expected, "\n"), strings.Join(actual, "\n")))
+	}
+
+	_, err = lint.Lint(f, map[string]model.LintRule{"unknown": {}})
+	if err == nil {
+		t.Errorf("unknown rules should be an error")
+	}
+	_, err = lint.Lint(f, map[string]model.LintRule{"tag-count": {Severity: "fatal"}})
+	if err == nil {
+		t.Errorf("unknown severities should be an error")
+	}
+}
+
+func TestFix(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	f := newFile(t, "<!--\ntags:\n- Go\n- go\n-->\n\n# Title\n\nSee https://example.com.\n\n```\ncode https://example.com")
+	post, fixed, err := lint.Fix(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fixed {
+		t.Fatal("the post should be fixed")
+	}
+	if len(post.Tags) != 1 || post.Tags[0].Name != "Go" {
+		t.Errorf("wrong tags: %v", post.Tags)
+	}
+	expected := "See <https://example.com>.\n\n```\ncode https://example.com\n```"
+	if post.Body != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, post.Body))
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	err := lint.WriteSARIF(buf, []lint.Diagnostic{
+		{Path: "mine/a.md", Line: 3, Column: 5, Rule: "bare-url", Severity: lint.SeverityWarning, Message: "bare"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine, StartColumn int }
+					}
+				}
+			}
+		}
+	}
+	err = json.Unmarshal(buf.Bytes(), &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("wrong log: %s", buf.String())
+	}
+	r := log.Runs[0].Results[0]
+	l := r.Locations[0].PhysicalLocation
+	if r.RuleID != "bare-url" || r.Level != "warning" || l.ArtifactLocation.URI != "mine/a.md" || l.Region.StartLine != 3 || l.Region.StartColumn != 5 {
+		t.Errorf("wrong result: %s", buf.String())
+	}
+}
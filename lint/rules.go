@@ -0,0 +1,307 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/minodisk/qiitactl/model"
+)
+
+func init() {
+	Register(TagCount{})
+	Register(TagChars{})
+	Register(DuplicateTags{})
+	Register(TitleLength{})
+	Register(EmptyBody{})
+	Register(UnclosedCodeFence{})
+	Register(HeadingLevel{})
+	Register(ImageAlt{})
+	Register(BareURL{})
+}
+
+// TagCount checks the number of the tags.
+// Qiita requires 1 to 5 tags, while the posts in teams can have no tags.
+type TagCount struct{}
+
+func (r TagCount) Name() string        { return "tag-count" }
+func (r TagCount) Description() string { return "The number of tags must be in the range." }
+func (r TagCount) Severity() Severity  { return SeverityError }
+
+func (r TagCount) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	min, max := c.Min, c.Max
+	if min == 0 && f.Post.Team == nil {
+		min = 1
+	}
+	if max == 0 {
+		max = 5
+	}
+	n := len(f.Post.Tags)
+	if n < min || n > max {
+		diags = append(diags, Diagnostic{
+			Line:    f.metaLine("tags"),
+			Column:  1,
+			Message: fmt.Sprintf("%d tags are given, but %d to %d tags are required", n, min, max),
+		})
+	}
+	return
+}
+
+var rInvalidTagChars = regexp.MustCompile("[\\s,<>\"'`?&%]")
+
+// TagChars checks the characters of the names of the tags.
+// Spaces and the characters which break URLs aren't allowed.
+type TagChars struct{}
+
+func (r TagChars) Name() string        { return "tag-chars" }
+func (r TagChars) Description() string { return "Tag names must not have invalid characters." }
+func (r TagChars) Severity() Severity  { return SeverityError }
+
+func (r TagChars) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	for _, tag := range f.Post.Tags {
+		if tag.Name == "" || rInvalidTagChars.MatchString(tag.Name) {
+			diags = append(diags, Diagnostic{
+				Line:    f.metaLine("tags"),
+				Column:  1,
+				Message: fmt.Sprintf("the tag %q has invalid characters", tag.Name),
+			})
+		}
+	}
+	return
+}
+
+// DuplicateTags checks the tags with the same name ignoring case.
+type DuplicateTags struct{}
+
+func (r DuplicateTags) Name() string        { return "duplicate-tags" }
+func (r DuplicateTags) Description() string { return "Tags must not be duplicated." }
+func (r DuplicateTags) Severity() Severity  { return SeverityError }
+
+func (r DuplicateTags) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	seen := make(map[string]bool)
+	for _, tag := range f.Post.Tags {
+		name := strings.ToLower(tag.Name)
+		if seen[name] {
+			diags = append(diags, Diagnostic{
+				Line:    f.metaLine("tags"),
+				Column:  1,
+				Message: fmt.Sprintf("the tag %q is duplicated", tag.Name),
+			})
+		}
+		seen[name] = true
+	}
+	return
+}
+
+// Fix removes the duplicated tags after the first ones.
+func (r DuplicateTags) Fix(post *model.Post, c model.LintRule) {
+	seen := make(map[string]bool)
+	var tags model.Tags
+	for _, tag := range post.Tags {
+		name := strings.ToLower(tag.Name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, tag)
+	}
+	post.Tags = tags
+}
+
+// TitleLength checks the number of the characters of the title.
+type TitleLength struct{}
+
+func (r TitleLength) Name() string        { return "title-length" }
+func (r TitleLength) Description() string { return "The length of the title must be in the range." }
+func (r TitleLength) Severity() Severity  { return SeverityError }
+
+func (r TitleLength) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	min, max := c.Min, c.Max
+	if min == 0 {
+		min = 1
+	}
+	if max == 0 {
+		max = 255
+	}
+	n := utf8.RuneCountInString(f.Post.Title)
+	if n < min || n > max {
+		diags = append(diags, Diagnostic{
+			Line:    f.titleLine(),
+			Column:  1,
+			Message: fmt.Sprintf("the title has %d characters, but %d to %d characters are required", n, min, max),
+		})
+	}
+	return
+}
+
+// EmptyBody checks the body isn't empty.
+type EmptyBody struct{}
+
+func (r EmptyBody) Name() string        { return "empty-body" }
+func (r EmptyBody) Description() string { return "The body must not be empty." }
+func (r EmptyBody) Severity() Severity  { return SeverityError }
+
+func (r EmptyBody) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	if strings.TrimSpace(f.Post.Body) == "" {
+		diags = append(diags, Diagnostic{
+			Line:    f.titleLine(),
+			Column:  1,
+			Message: "the body is empty",
+		})
+	}
+	return
+}
+
+// UnclosedCodeFence checks the code blocks are closed.
+type UnclosedCodeFence struct{}
+
+func (r UnclosedCodeFence) Name() string        { return "unclosed-code-fence" }
+func (r UnclosedCodeFence) Description() string { return "Code blocks must be closed." }
+func (r UnclosedCodeFence) Severity() Severity  { return SeverityError }
+
+func (r UnclosedCodeFence) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	if _, unclosed := f.bodyLines(); unclosed > 0 {
+		diags = append(diags, Diagnostic{
+			Line:    unclosed,
+			Column:  1,
+			Message: "the code block isn't closed",
+		})
+	}
+	return
+}
+
+// Fix closes the code block at the end of the body.
+func (r UnclosedCodeFence) Fix(post *model.Post, c model.LintRule) {
+	lines, unclosed := scanBody(post.Body, 1)
+	if unclosed == 0 {
+		return
+	}
+	fence := rFence.FindStringSubmatch(lines[unclosed-1].Text)[1]
+	post.Body = post.Body + "\n" + fence
+}
+
+var rHeading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]|$)`)
+
+// HeadingLevel checks the headings don't skip levels.
+// The title is taken as the heading of level 1.
+type HeadingLevel struct{}
+
+func (r HeadingLevel) Name() string        { return "heading-level" }
+func (r HeadingLevel) Description() string { return "Headings must not skip levels." }
+func (r HeadingLevel) Severity() Severity  { return SeverityWarning }
+
+func (r HeadingLevel) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	level := 1
+	lines, _ := f.bodyLines()
+	for _, line := range lines {
+		if line.Code {
+			continue
+		}
+		m := rHeading.FindStringSubmatch(line.Text)
+		if m == nil {
+			continue
+		}
+		next := len(m[1])
+		if next > level+1 {
+			diags = append(diags, Diagnostic{
+				Line:    line.Number,
+				Column:  strings.Index(line.Text, "#") + 1,
+				Message: fmt.Sprintf("the heading of level %d follows the one of level %d", next, level),
+			})
+		}
+		level = next
+	}
+	return
+}
+
+var rImageWithoutAlt = regexp.MustCompile(`!\[\s*\]\(`)
+
+// ImageAlt checks the images have alternative text.
+type ImageAlt struct{}
+
+func (r ImageAlt) Name() string        { return "image-alt" }
+func (r ImageAlt) Description() string { return "Images must have alternative text." }
+func (r ImageAlt) Severity() Severity  { return SeverityWarning }
+
+func (r ImageAlt) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	lines, _ := f.bodyLines()
+	for _, line := range lines {
+		if line.Code {
+			continue
+		}
+		for _, loc := range rImageWithoutAlt.FindAllStringIndex(maskCode(line.Text), -1) {
+			diags = append(diags, Diagnostic{
+				Line:    line.Number,
+				Column:  column(line.Text, loc[0]),
+				Message: "the image has no alternative text",
+			})
+		}
+	}
+	return
+}
+
+var rURL = regexp.MustCompile(`https?://[^\s<>()\[\]]+`)
+
+// bareURLs returns the ranges of the URLs written without any notation in text.
+func bareURLs(text string) (locs [][]int) {
+	masked := maskCode(text)
+	for _, loc := range rURL.FindAllStringIndex(masked, -1) {
+		before := strings.TrimRight(masked[:loc[0]], " ")
+		if loc[0] > 0 && strings.IndexByte("(<[\"'=", masked[loc[0]-1]) >= 0 {
+			continue
+		}
+		// The definitions of references: [name]: https://...
+		if strings.HasSuffix(before, "]:") {
+			continue
+		}
+		// The punctuation after a URL isn't a part of it.
+		for loc[1] > loc[0] && strings.IndexByte(".,:;!?", masked[loc[1]-1]) >= 0 {
+			loc[1]--
+		}
+		locs = append(locs, loc)
+	}
+	return
+}
+
+// BareURL checks the URLs are written as links.
+type BareURL struct{}
+
+func (r BareURL) Name() string        { return "bare-url" }
+func (r BareURL) Description() string { return "URLs must be written as links." }
+func (r BareURL) Severity() Severity  { return SeverityWarning }
+
+func (r BareURL) Check(f File, c model.LintRule) (diags []Diagnostic) {
+	lines, _ := f.bodyLines()
+	for _, line := range lines {
+		if line.Code {
+			continue
+		}
+		for _, loc := range bareURLs(line.Text) {
+			diags = append(diags, Diagnostic{
+				Line:    line.Number,
+				Column:  column(line.Text, loc[0]),
+				Message: fmt.Sprintf("the URL %s isn't written as a link", line.Text[loc[0]:loc[1]]),
+			})
+		}
+	}
+	return
+}
+
+// Fix encloses the bare URLs with angle brackets.
+func (r BareURL) Fix(post *model.Post, c model.LintRule) {
+	lines, _ := scanBody(post.Body, 1)
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+		if line.Code {
+			continue
+		}
+		locs := bareURLs(line.Text)
+		for j := len(locs) - 1; j >= 0; j-- {
+			loc := locs[j]
+			texts[i] = texts[i][:loc[0]] + "<" + texts[i][loc[0]:loc[1]] + ">" + texts[i][loc[1]:]
+		}
+	}
+	post.Body = strings.Join(texts, "\n")
+}
@@ -0,0 +1,396 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/lint"
+	"github.com/minodisk/qiitactl/model"
+)
+
+// RuleRequired is the name of the rule reported for the fields which Validate requires.
+const RuleRequired = "required"
+
+// requiredRules are the lint rules which report the same problems as Validate for the fields.
+var requiredRules = map[string]string{
+	"title": "title-length",
+	"body":  "empty-body",
+	"tags":  "tag-count",
+}
+
+// splitLines splits text into the lines without line terminators.
+func splitLines(text string) (lines []string) {
+	lines = strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return
+}
+
+// diagnose checks the document with Decode, Validate and the lint rules configured in the workspace.
+func (s *Server) diagnose(uri string) (diags []Diagnostic, err error) {
+	diags = []Diagnostic{}
+	text := s.docs[uri]
+	path := s.path(uri)
+	lines := splitLines(text)
+	f, e := lint.Parse(path, []byte(text))
+	if e != nil {
+		diags = append(diags, diagnostic(lines, lint.Unparsable(path, e)))
+		return
+	}
+	config, err := model.LoadConfig()
+	if err != nil {
+		return
+	}
+	ds, err := lint.Lint(f, config.Lint)
+	if err != nil {
+		return
+	}
+
+	invalid := f.Post.Validate()
+	var names []string
+	for name := range invalid {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reported := make(map[string]bool)
+	for _, name := range names {
+		reported[requiredRules[name]] = true
+		diags = append(diags, diagnostic(lines, lint.Diagnostic{
+			Line:     f.FieldLine(name),
+			Column:   1,
+			Rule:     RuleRequired,
+			Severity: lint.SeverityError,
+			Message:  fmt.Sprintf("%s shouldn't be empty", name),
+		}))
+	}
+	for _, d := range ds {
+		if reported[d.Rule] {
+			continue
+		}
+		diags = append(diags, diagnostic(lines, d))
+	}
+	return
+}
+
+func (s *Server) publishDiagnostics(uri string) (err error) {
+	diags, err := s.diagnose(uri)
+	if err != nil {
+		return
+	}
+	err = s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diags,
+	})
+	return
+}
+
+// diagnostic converts d into the diagnostic of the protocol ranging to the end of the line.
+func diagnostic(lines []string, d lint.Diagnostic) Diagnostic {
+	line := d.Line - 1
+	if line >= len(lines) {
+		line = len(lines) - 1
+	}
+	if line < 0 {
+		line = 0
+	}
+	text := lines[line]
+	// The column is counted in characters.
+	start := len(text)
+	n := 0
+	for i := range text {
+		if n == d.Column-1 {
+			start = i
+			break
+		}
+		n++
+	}
+	severity := severityError
+	if d.Severity == lint.SeverityWarning {
+		severity = severityWarning
+	}
+	return Diagnostic{
+		Range: Range{
+			Start: Position{Line: line, Character: character(text, start)},
+			End:   Position{Line: line, Character: character(text, len(text))},
+		},
+		Severity: severity,
+		Code:     d.Rule,
+		Source:   "qiitactl",
+		Message:  d.Message,
+	}
+}
+
+// header returns the range of the lines between the delimiters of the header, as [start, end).
+// The header isn't closed while the meta is being typed, then it ranges to the end.
+func header(lines []string) (start, end int, ok bool) {
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i == len(lines) {
+		return
+	}
+	var closing string
+	switch strings.TrimSpace(lines[i]) {
+	case "<!--":
+		closing = "-->"
+	case "---":
+		closing = "---"
+	default:
+		return
+	}
+	start = i + 1
+	end = len(lines)
+	ok = true
+	for j := start; j < len(lines); j++ {
+		if strings.TrimSpace(lines[j]) == closing {
+			end = j
+			return
+		}
+	}
+	return
+}
+
+var (
+	rTopKey    = regexp.MustCompile(`^([A-Za-z_]+):`)
+	rListItem  = regexp.MustCompile(`^\s*-\s*[^\s:]*$`)
+	rTeamID    = regexp.MustCompile(`^\s+id:\s*\S*$`)
+	rBoolValue = regexp.MustCompile(`^(private|coediting|slide|draft):\s*\S*$`)
+)
+
+// complete returns the candidates at the position in the header:
+// the names of tags under tags, the IDs of teams under team and the booleans of the flags.
+func (s *Server) complete(p TextDocumentPositionParams) (items []CompletionItem) {
+	items = []CompletionItem{}
+	lines := splitLines(s.docs[p.TextDocument.URI])
+	start, end, ok := header(lines)
+	line := p.Position.Line
+	if !ok || line < start || line >= end {
+		return
+	}
+	prefix := lines[line][:offset(lines[line], p.Position.Character)]
+	key := ""
+	for i := line; i >= start; i-- {
+		if m := rTopKey.FindStringSubmatch(lines[i]); m != nil {
+			key = m[1]
+			break
+		}
+	}
+
+	switch {
+	case key == "tags" && rListItem.MatchString(prefix):
+		items = s.loadCatalog().tags
+	case key == "team" && rTeamID.MatchString(prefix):
+		items = s.loadCatalog().teams
+	case rBoolValue.MatchString(prefix):
+		for _, b := range []string{"true", "false"} {
+			items = append(items, CompletionItem{
+				Label: b,
+				Kind:  completionKindKeyword,
+			})
+		}
+	}
+	return
+}
+
+// catalog is the candidates of completion collected from the workspace.
+type catalog struct {
+	tags  []CompletionItem
+	teams []CompletionItem
+}
+
+// loadCatalog collects the tags used in the local posts ordered by how many posts use them,
+// and the teams of the local posts and the teams which the user belongs to.
+// The catalog is kept until a document is saved.
+func (s *Server) loadCatalog() *catalog {
+	if s.catalog != nil {
+		return s.catalog
+	}
+	c := &catalog{
+		tags:  []CompletionItem{},
+		teams: []CompletionItem{},
+	}
+	counts := make(map[string]int)
+	names := make(map[string]string)
+	teams := make(map[string]string)
+	model.WalkPosts(".", func(post model.Post) error {
+		for _, tag := range post.Tags {
+			key := strings.ToLower(tag.Name)
+			if _, ok := names[key]; !ok {
+				names[key] = tag.Name
+			}
+			counts[key]++
+		}
+		if post.Team != nil && post.Team.ID != "" {
+			teams[post.Team.ID] = post.Team.Name
+		}
+		return nil
+	})
+	if ts, err := model.FetchTeams(s.Client); err == nil {
+		for _, team := range ts {
+			teams[team.ID] = team.Name
+		}
+	}
+
+	var keys []string
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for i, key := range keys {
+		c.tags = append(c.tags, CompletionItem{
+			Label:    names[key],
+			Kind:     completionKindValue,
+			Detail:   fmt.Sprintf("used in %d posts", counts[key]),
+			SortText: fmt.Sprintf("%05d", i),
+		})
+	}
+	var ids []string
+	for id := range teams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c.teams = append(c.teams, CompletionItem{
+			Label:  id,
+			Kind:   completionKindValue,
+			Detail: teams[id],
+		})
+	}
+	s.catalog = c
+	return c
+}
+
+// hover shows the state of the post compared with Qiita and the reactions to it,
+// when the position is in the header or on the title.
+func (s *Server) hover(p TextDocumentPositionParams) (hover *Hover) {
+	uri := p.TextDocument.URI
+	text := s.docs[uri]
+	f, err := lint.Parse(s.path(uri), []byte(text))
+	if err != nil {
+		return
+	}
+	_, end, ok := header(splitLines(text))
+	line := p.Position.Line
+	if !ok || line > end && line != f.FieldLine("title")-1 {
+		return
+	}
+
+	post := f.Post
+	buf := bytes.NewBuffer([]byte{})
+	fmt.Fprintf(buf, "**%s**\n\n", post.Title)
+	if post.ID == "" {
+		fmt.Fprint(buf, "Not published yet")
+		if post.PublishAt != nil {
+			fmt.Fprintf(buf, ": it will be published at %s", post.PublishAt.Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintln(buf)
+	} else {
+		fmt.Fprintf(buf, "%s\n\n", post.URL)
+		remote, engagement, err := model.FetchPostEngagement(s.Client, post.Team, post.ID)
+		var remotes model.Posts
+		if err == nil {
+			remotes = model.Posts{remote}
+		} else if e, ok := err.(api.ResponseError); !ok || e.Type != "not_found" {
+			fmt.Fprintf(buf, "Couldn't fetch the post from Qiita: %s\n", err)
+			hover = &Hover{Contents: MarkupContent{Kind: "markdown", Value: buf.String()}}
+			return
+		}
+		statuses, err := model.Statuses(model.Posts{post}, remotes)
+		state := "up to date"
+		if err == nil && len(statuses) > 0 {
+			state = strings.Replace(string(statuses[0].State), "_", " ", -1)
+		}
+		fmt.Fprintf(buf, "Status: %s\n", state)
+		if remotes != nil {
+			fmt.Fprintf(buf, "\nLikes: %d, Comments: %d, Stocks: %d", engagement.LikesCount, engagement.CommentsCount, engagement.StocksCount)
+			if engagement.PageViewsCount != nil {
+				fmt.Fprintf(buf, ", Views: %d", *engagement.PageViewsCount)
+			}
+			fmt.Fprintln(buf)
+		}
+	}
+	hover = &Hover{Contents: MarkupContent{Kind: "markdown", Value: buf.String()}}
+	return
+}
+
+// codeActions returns the action to publish the draft or to update the post.
+func (s *Server) codeActions(p CodeActionParams) (actions []CodeAction) {
+	actions = []CodeAction{}
+	uri := p.TextDocument.URI
+	f, err := lint.Parse(s.path(uri), []byte(s.docs[uri]))
+	if err != nil {
+		return
+	}
+	var title, command string
+	switch {
+	case f.Post.ID == "":
+		title, command = "Publish in Qiita", CommandPublish
+	case !f.Post.Draft:
+		title, command = "Update in Qiita", CommandUpdate
+	default:
+		return
+	}
+	actions = append(actions, CodeAction{
+		Title: title,
+		Kind:  "source",
+		Command: &Command{
+			Title:     title,
+			Command:   command,
+			Arguments: []interface{}{uri},
+		},
+	})
+	return
+}
+
+// executeCommand publishes or updates the post with the saved file,
+// and shows the result as a message.
+func (s *Server) executeCommand(p ExecuteCommandParams) (err error) {
+	var fn func(path string) (model.Post, error)
+	var done string
+	switch p.Command {
+	case CommandPublish:
+		fn, done = s.Publish, "published"
+	case CommandUpdate:
+		fn, done = s.Update, "updated"
+	}
+	var uri string
+	if len(p.Arguments) > 0 {
+		json.Unmarshal(p.Arguments[0], &uri)
+	}
+	if fn == nil || uri == "" {
+		err = rpcError{
+			Code:    codeInvalidParams,
+			Message: fmt.Sprintf("unknown command: %s", p.Command),
+		}
+		return
+	}
+
+	path := s.path(uri)
+	if text, ok := s.docs[uri]; ok {
+		b, e := ioutil.ReadFile(path)
+		if e == nil && string(b) != text {
+			err = s.showMessage(messageTypeError, fmt.Sprintf("%s has unsaved changes: save it first", path))
+			return
+		}
+	}
+	post, e := fn(path)
+	if e != nil {
+		err = s.showMessage(messageTypeError, e.Error())
+		return
+	}
+	err = s.showMessage(messageTypeInfo, fmt.Sprintf("%s %s %s", done, post.Path, post.URL))
+	return
+}
@@ -0,0 +1,157 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Error codes of JSON-RPC.
+const (
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
+
+// message is a request, a response or a notification of JSON-RPC 2.0.
+// Notifications have no ID.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   rpcError        `json:"error"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// rpcError is returned to the client as the error of a request.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err rpcError) Error() string {
+	return err.Message
+}
+
+// conn reads and writes the messages framed with Content-Length headers.
+type conn struct {
+	r  *bufio.Reader
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		r: bufio.NewReader(r),
+		w: w,
+	}
+}
+
+// read reads a message.
+// io.EOF is returned when the input is closed between messages.
+func (c *conn) read() (msg message, err error) {
+	length := -1
+	for {
+		var line string
+		line, err = c.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && (line != "" || length >= 0) {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			err = fmt.Errorf("lsp: malformed header %q", line)
+			return
+		}
+		if strings.EqualFold(strings.TrimSpace(line[:i]), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(line[i+1:]))
+			if err != nil {
+				return
+			}
+		}
+	}
+	if length < 0 {
+		err = fmt.Errorf("lsp: Content-Length is missing")
+		return
+	}
+	b := make([]byte, length)
+	_, err = io.ReadFull(c.r, b)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &msg)
+	return
+}
+
+func (c *conn) write(v interface{}) (err error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return
+}
+
+// reply responds to the request with id.
+// e is sent as the error of the request, or the result is sent when e is nil.
+func (c *conn) reply(id json.RawMessage, result interface{}, e error) (err error) {
+	if e == nil {
+		err = c.write(response{
+			JSONRPC: "2.0",
+			ID:      id,
+			Result:  result,
+		})
+		return
+	}
+	re, ok := e.(rpcError)
+	if !ok {
+		re = rpcError{
+			Code:    codeInternalError,
+			Message: e.Error(),
+		}
+	}
+	err = c.write(errorResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   re,
+	})
+	return
+}
+
+func (c *conn) notify(method string, params interface{}) (err error) {
+	err = c.write(notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	return
+}
@@ -0,0 +1,208 @@
+// Package lsp serves the Language Server Protocol for the files of posts,
+// so that editors check and complete the meta while editing.
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/model"
+)
+
+// Server is a language server for the files of posts in the workspace of current working directory.
+type Server struct {
+	// Client is used to show the posts in Qiita and to complete the IDs of teams.
+	Client api.Client
+	// Publish creates a new post in Qiita with the draft at path.
+	Publish func(path string) (post model.Post, err error)
+	// Update updates the post in Qiita with the file at path.
+	Update func(path string) (post model.Post, err error)
+
+	conn     *conn
+	docs     map[string]string // 開いているドキュメントのURIと内容
+	catalog  *catalog
+	shutdown bool
+}
+
+// Serve reads the messages from in and writes the messages to out
+// until the client sends exit or closes in.
+func (s *Server) Serve(in io.Reader, out io.Writer) (err error) {
+	s.conn = newConn(in, out)
+	s.docs = make(map[string]string)
+	for {
+		var msg message
+		msg, err = s.conn.read()
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				err = fmt.Errorf("lsp: exited without shutdown")
+			}
+			return
+		}
+
+		result, e := s.handle(msg)
+		if len(msg.ID) == 0 {
+			if e != nil {
+				err = s.showMessage(messageTypeError, e.Error())
+			}
+		} else {
+			err = s.conn.reply(msg.ID, result, e)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handle(msg message) (result interface{}, err error) {
+	switch msg.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": textDocumentSyncFull,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"-", " ", ":"},
+				},
+				"hoverProvider":      true,
+				"codeActionProvider": true,
+				"executeCommandProvider": map[string]interface{}{
+					"commands": []string{CommandPublish, CommandUpdate},
+				},
+			},
+			"serverInfo": map[string]string{
+				"name": "qiitactl",
+			},
+		}
+	case "initialized":
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		err = unmarshalParams(msg, &p)
+		if err != nil {
+			return
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		err = s.publishDiagnostics(p.TextDocument.URI)
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		err = unmarshalParams(msg, &p)
+		if err != nil {
+			return
+		}
+		// The whole text is sent with each change, since the server requires full sync.
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		err = s.publishDiagnostics(p.TextDocument.URI)
+	case "textDocument/didSave":
+		var p DidSaveTextDocumentParams
+		err = unmarshalParams(msg, &p)
+		if err != nil {
+			return
+		}
+		if p.Text != nil {
+			s.docs[p.TextDocument.URI] = *p.Text
+		}
+		// The tags and teams in the saved file are completed from now on.
+		s.catalog = nil
+		err = s.publishDiagnostics(p.TextDocument.URI)
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		err = unmarshalParams(msg, &p)
+		if err != nil {
+			return
+		}
+		delete(s.docs, p.TextDocument.URI)
+		err = s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         p.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		err = unmarshalParams(msg, &p)
+		if err != nil {
+			return
+		}
+		result = s.complete(p)
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		err = unmarshalParams(msg, &p)
+		if err != nil {
+			return
+		}
+		result = s.hover(p)
+	case "textDocument/codeAction":
+		var p CodeActionParams
+		err = unmarshalParams(msg, &p)
+		if err != nil {
+			return
+		}
+		result = s.codeActions(p)
+	case "workspace/executeCommand":
+		var p ExecuteCommandParams
+		err = unmarshalParams(msg, &p)
+		if err != nil {
+			return
+		}
+		err = s.executeCommand(p)
+	default:
+		// Unknown notifications are ignored as the protocol requires.
+		if len(msg.ID) > 0 {
+			err = rpcError{
+				Code:    codeMethodNotFound,
+				Message: fmt.Sprintf("method not found: %s", msg.Method),
+			}
+		}
+	}
+	return
+}
+
+func unmarshalParams(msg message, params interface{}) (err error) {
+	err = json.Unmarshal(msg.Params, params)
+	if err != nil {
+		err = rpcError{
+			Code:    codeInvalidParams,
+			Message: fmt.Sprintf("invalid params of %s: %s", msg.Method, err),
+		}
+	}
+	return
+}
+
+func (s *Server) showMessage(typ int, msg string) error {
+	return s.conn.notify("window/showMessage", ShowMessageParams{
+		Type:    typ,
+		Message: msg,
+	})
+}
+
+// path returns the path of the file with uri,
+// which is relative to current working directory when the file is in it.
+func (s *Server) path(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := filepath.FromSlash(u.Path)
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
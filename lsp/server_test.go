@@ -0,0 +1,341 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/api"
+	"github.com/minodisk/qiitactl/info"
+	"github.com/minodisk/qiitactl/lsp"
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+type received struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+// session frames the messages to be sent to the server.
+type session struct {
+	buf bytes.Buffer
+	id  int
+}
+
+func (s *session) send(method string, params interface{}, request bool) (id int) {
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	}
+	if request {
+		s.id++
+		id = s.id
+		msg["id"] = id
+	}
+	b, _ := json.Marshal(msg)
+	fmt.Fprintf(&s.buf, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return
+}
+
+func (s *session) request(method string, params interface{}) int {
+	return s.send(method, params, true)
+}
+
+func (s *session) notify(method string, params interface{}) {
+	s.send(method, params, false)
+}
+
+func readAll(t *testing.T, r io.Reader) (msgs []received) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		br.ReadString('\n')
+		b := make([]byte, length)
+		_, err = io.ReadFull(br, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg received
+		err = json.Unmarshal(b, &msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func response(msgs []received, id int) (msg received, ok bool) {
+	for _, msg = range msgs {
+		if msg.ID != nil && *msg.ID == id {
+			ok = true
+			return
+		}
+	}
+	return
+}
+
+func notifications(msgs []received, method string) (params []json.RawMessage) {
+	for _, msg := range msgs {
+		if msg.ID == nil && msg.Method == method {
+			params = append(params, msg.Params)
+		}
+	}
+	return
+}
+
+func uri(t *testing.T, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + filepath.ToSlash(abs)
+}
+
+func position(uri string, line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     map[string]int{"line": line, "character": character},
+	}
+}
+
+func TestServer(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/teams", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"active": true, "id": "increments", "name": "Increments Inc."}]`))
+	})
+	mux.HandleFunc("/api/v2/items/00000000000000000001", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "00000000000000000001", "title": "Published", "body": "body", "tags": [{"name": "Go"}], "updated_at": "2000-01-01T00:00:00+00:00", "likes_count": 3, "comments_count": 1, "stocks_count": 2, "page_views_count": 120}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	client := api.NewClient(func(subDomain, path string) string {
+		return fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+	}, info.Info{})
+
+	files := map[string]string{
+		"mine/go-1.md":      "<!--\ntags:\n- Go\n-->\n\n# Go 1\n\nbody",
+		"mine/go-2.md":      "<!--\ntags:\n- go\n- Docker\n-->\n\n# Go 2\n\nbody",
+		"mine/published.md": "<!--\nid: 00000000000000000001\nurl: https://qiita.com/foo/items/00000000000000000001\nupdated_at: 2000-01-01T09:00:00+09:00\ntags:\n- Go\n-->\n\n# Published\n\nbody",
+	}
+	for path, content := range files {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	draft := "<!--\ndraft: true\nprivate: false\n-->\n\n# Draft\n\nSee https://example.com"
+	err := ioutil.WriteFile("mine/draft.md", []byte(draft), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	draftURI := uri(t, "mine/draft.md")
+	// The meta being typed can't be decoded yet.
+	typing := "<!--\nprivate: \nteam:\n  id: \ntags:\n- \n-->\n\n# Typing\n\nbody"
+	typingURI := uri(t, "mine/typing.md")
+	publishedURI := uri(t, "mine/published.md")
+	brokenURI := uri(t, "mine/broken.md")
+
+	var s session
+	initialize := s.request("initialize", map[string]interface{}{})
+	s.notify("initialized", map[string]interface{}{})
+	s.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": brokenURI, "languageId": "markdown", "version": 1, "text": "no header"},
+	})
+	s.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": draftURI, "languageId": "markdown", "version": 1, "text": draft},
+	})
+	s.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": typingURI, "languageId": "markdown", "version": 1, "text": typing},
+	})
+	tags := s.request("textDocument/completion", position(typingURI, 5, 2))
+	teams := s.request("textDocument/completion", position(typingURI, 3, 6))
+	bools := s.request("textDocument/completion", position(typingURI, 1, 9))
+	body := s.request("textDocument/completion", position(typingURI, 10, 0))
+	actions := s.request("textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]string{"uri": draftURI},
+		"range":        map[string]interface{}{"start": map[string]int{}, "end": map[string]int{}},
+	})
+	s.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": publishedURI, "languageId": "markdown", "version": 1, "text": files["mine/published.md"]},
+	})
+	hover := s.request("textDocument/hover", position(publishedURI, 1, 0))
+	hoverBody := s.request("textDocument/hover", position(publishedURI, 9, 0))
+	publish := s.request("workspace/executeCommand", map[string]interface{}{
+		"command":   lsp.CommandPublish,
+		"arguments": []string{draftURI},
+	})
+	s.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]string{"uri": publishedURI},
+		"contentChanges": []map[string]string{{"text": files["mine/published.md"] + " changed"}},
+	})
+	unsaved := s.request("workspace/executeCommand", map[string]interface{}{
+		"command":   lsp.CommandUpdate,
+		"arguments": []string{publishedURI},
+	})
+	unknown := s.request("textDocument/definition", position(draftURI, 0, 0))
+	shutdown := s.request("shutdown", nil)
+	s.notify("exit", nil)
+
+	var published []string
+	out := bytes.NewBuffer([]byte{})
+	ls := lsp.Server{
+		Client: client,
+		Publish: func(path string) (post model.Post, err error) {
+			published = append(published, path)
+			post.Path = path
+			post.URL = "https://qiita.com/foo/items/00000000000000000002"
+			return
+		},
+		Update: func(path string) (post model.Post, err error) {
+			t.Errorf("the file with unsaved changes shouldn't be updated: %s", path)
+			return
+		},
+	}
+	err = ls.Serve(&s.buf, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := readAll(t, out)
+
+	msg, _ := response(msgs, initialize)
+	if !bytes.Contains(msg.Result, []byte(`"hoverProvider":true`)) || !bytes.Contains(msg.Result, []byte(lsp.CommandUpdate)) {
+		t.Errorf("wrong capabilities: %s", msg.Result)
+	}
+
+	var diags []lsp.PublishDiagnosticsParams
+	for _, params := range notifications(msgs, "textDocument/publishDiagnostics") {
+		var p lsp.PublishDiagnosticsParams
+		json.Unmarshal(params, &p)
+		diags = append(diags, p)
+	}
+	if len(diags) != 5 {
+		t.Fatalf("diagnostics should be published for each opened or changed document: %v", diags)
+	}
+	if len(diags[0].Diagnostics) != 1 || diags[0].Diagnostics[0].Code != "format" {
+		t.Errorf("wrong diagnostics of the broken file: %v", diags[0].Diagnostics)
+	}
+	var codes []string
+	for _, d := range diags[1].Diagnostics {
+		codes = append(codes, fmt.Sprintf("%d:%d-%d %s", d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Character, d.Code))
+	}
+	if strings.Join(codes, ",") != "0:0-4 required,7:4-23 bare-url" {
+		t.Errorf("wrong diagnostics of the draft: %v", codes)
+	}
+	if len(diags[3].Diagnostics) != 0 {
+		t.Errorf("the published post should have no problems: %v", diags[2].Diagnostics)
+	}
+
+	labels := func(id int) (labels []string) {
+		msg, _ := response(msgs, id)
+		var items []lsp.CompletionItem
+		json.Unmarshal(msg.Result, &items)
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+		return
+	}
+	if l := strings.Join(labels(tags), ","); l != "Go,Docker" {
+		t.Errorf("wrong tags: %s", l)
+	}
+	if l := strings.Join(labels(teams), ","); l != "increments" {
+		t.Errorf("wrong teams: %s", l)
+	}
+	if l := strings.Join(labels(bools), ","); l != "true,false" {
+		t.Errorf("wrong booleans: %s", l)
+	}
+	if l := labels(body); len(l) != 0 {
+		t.Errorf("nothing should be completed in the body: %v", l)
+	}
+
+	msg, _ = response(msgs, actions)
+	if !bytes.Contains(msg.Result, []byte(lsp.CommandPublish)) {
+		t.Errorf("wrong code actions: %s", msg.Result)
+	}
+
+	msg, _ = response(msgs, hover)
+	var h lsp.Hover
+	json.Unmarshal(msg.Result, &h)
+	expected := "**Published**\n\nhttps://qiita.com/foo/items/00000000000000000001\n\nStatus: up to date\n\nLikes: 3, Comments: 1, Stocks: 2, Views: 120\n"
+	if h.Contents.Value != expected {
+		t.Errorf("wrong hover:\n%s", testutil.Diff(expected, h.Contents.Value))
+	}
+	msg, _ = response(msgs, hoverBody)
+	if string(msg.Result) != "null" {
+		t.Errorf("nothing should be shown in the body: %s", msg.Result)
+	}
+
+	if len(published) != 1 || published[0] != filepath.Join("mine", "draft.md") {
+		t.Errorf("the draft should be published: %v", published)
+	}
+	if _, ok := response(msgs, publish); !ok {
+		t.Error("executeCommand should be responded")
+	}
+	var messages []string
+	for _, params := range notifications(msgs, "window/showMessage") {
+		var p lsp.ShowMessageParams
+		json.Unmarshal(params, &p)
+		messages = append(messages, p.Message)
+	}
+	if len(messages) != 2 ||
+		messages[0] != "published mine/draft.md https://qiita.com/foo/items/00000000000000000002" ||
+		messages[1] != "mine/published.md has unsaved changes: save it first" {
+		t.Errorf("wrong messages: %v", messages)
+	}
+	if _, ok := response(msgs, unsaved); !ok {
+		t.Error("executeCommand should be responded")
+	}
+
+	msg, _ = response(msgs, unknown)
+	if msg.Error == nil || msg.Error.Code != -32601 {
+		t.Errorf("unknown methods should be reported: %v", msg)
+	}
+	msg, _ = response(msgs, shutdown)
+	if string(msg.Result) != "null" {
+		t.Errorf("wrong result of shutdown: %s", msg.Result)
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	var s session
+	s.notify("exit", nil)
+	var server lsp.Server
+	err := server.Serve(&s.buf, ioutil.Discard)
+	if err == nil {
+		t.Error("error should be returned when exiting without shutdown")
+	}
+}
@@ -18,5 +18,8 @@ func main() {
 	}
 	client := api.NewClient(nil, info)
 	cmd := command.New(info, client, os.Stdout, os.Stderr)
-	cmd.Run(os.Args)
+	err = cmd.Run(os.Args)
+	if err != nil {
+		os.Exit(1)
+	}
 }
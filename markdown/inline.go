@@ -0,0 +1,428 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	rAutolink   = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^<>\s]*)>`)
+	rEmail      = regexp.MustCompile(`^<([^\s<>@]+@[^\s<>@]+\.[^\s<>@]+)>`)
+	rInlineHTML = regexp.MustCompile(`^(?:<!--[\s\S]*?-->|</?[A-Za-z][A-Za-z0-9-]*(?:\s+[^<>]*)?/?>)`)
+	rBareURL    = regexp.MustCompile(`^https?://[^\s<]+`)
+	rEntity     = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	rBreak      = regexp.MustCompile(`(?: {2,}|\\)\n`)
+)
+
+func escape(s string) string {
+	var b bytes.Buffer
+	writeEscaped(&b, s)
+	return b.String()
+}
+
+func writeEscaped(b *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&quot;")
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// runLength returns the number of c repeated from i in s.
+func runLength(s string, i int, c byte) (n int) {
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return
+}
+
+// inline renders the inline elements in s.
+func (r *renderer) inline(s string) string {
+	s = rBreak.ReplaceAllString(s, "\n")
+	var b bytes.Buffer
+	for i := 0; i < len(s); {
+		n := r.inlineAt(&b, s, i)
+		if n > 0 {
+			i += n
+			continue
+		}
+		writeEscaped(&b, s[i:i+1])
+		i++
+	}
+	return b.String()
+}
+
+// inlineAt renders the inline element starting at i in s,
+// and returns the length of the consumed text or 0 when no element starts there.
+func (r *renderer) inlineAt(b *bytes.Buffer, s string, i int) (n int) {
+	switch s[i] {
+	case '\\':
+		if i+1 < len(s) && isPunct(s[i+1]) {
+			writeEscaped(b, s[i+1:i+2])
+			n = 2
+		}
+	case '\n':
+		b.WriteString("<br>\n")
+		n = 1 + len(s[i+1:]) - len(strings.TrimLeft(s[i+1:], " "))
+	case '`':
+		n = r.codeSpan(b, s, i)
+	case '$':
+		n = r.inlineMath(b, s, i)
+	case '!':
+		if i+1 < len(s) && s[i+1] == '[' {
+			n = r.link(b, s, i+1, true)
+			if n > 0 {
+				n++
+			}
+		}
+	case '[':
+		n = r.footnoteRef(b, s, i)
+		if n == 0 {
+			n = r.link(b, s, i, false)
+		}
+	case '<':
+		n = r.angle(b, s, i)
+	case '*', '_', '~':
+		n = r.emphasis(b, s, i)
+	case 'h':
+		n = r.bareURL(b, s, i)
+	case '&':
+		if m := rEntity.FindString(s[i:]); m != "" {
+			b.WriteString(m)
+			n = len(m)
+		}
+	}
+	return
+}
+
+// closingCode returns the index of the backticks closing the code span opened with n backticks before from.
+func closingCode(s string, from, n int) int {
+	for j := from; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(s, j, '`')
+		if m == n {
+			return j
+		}
+		j += m
+	}
+	return -1
+}
+
+func (r *renderer) codeSpan(b *bytes.Buffer, s string, i int) (n int) {
+	ticks := runLength(s, i, '`')
+	j := closingCode(s, i+ticks, ticks)
+	if j < 0 {
+		b.WriteString(s[i : i+ticks])
+		n = ticks
+		return
+	}
+	code := strings.Replace(s[i+ticks:j], "\n", " ", -1)
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+		code = code[1 : len(code)-1]
+	}
+	b.WriteString("<code>")
+	writeEscaped(b, code)
+	b.WriteString("</code>")
+	n = j + ticks - i
+	return
+}
+
+// inlineMath renders math between `$`, which doesn't start or end with spaces.
+func (r *renderer) inlineMath(b *bytes.Buffer, s string, i int) (n int) {
+	if i+1 >= len(s) || isSpace(s[i+1]) || s[i+1] == '$' {
+		return
+	}
+	for j := i + 2; j < len(s); j++ {
+		if s[j] != '$' {
+			continue
+		}
+		if isSpace(s[j-1]) || s[j-1] == '\\' || (j+1 < len(s) && s[j+1] >= '0' && s[j+1] <= '9') {
+			continue
+		}
+		b.WriteString("<span class=\"math\">\\(")
+		writeEscaped(b, s[i+1:j])
+		b.WriteString("\\)</span>")
+		n = j + 1 - i
+		return
+	}
+	return
+}
+
+func (r *renderer) footnoteRef(b *bytes.Buffer, s string, i int) (n int) {
+	if !strings.HasPrefix(s[i:], "[^") {
+		return
+	}
+	j := strings.IndexByte(s[i:], ']')
+	if j < 0 {
+		return
+	}
+	id := s[i+2 : i+j]
+	fn, ok := r.footnotes[id]
+	if !ok {
+		return
+	}
+	if fn.refs == 0 {
+		r.order = append(r.order, id)
+	}
+	fn.refs++
+	num := 0
+	for k, o := range r.order {
+		if o == id {
+			num = k + 1
+		}
+	}
+	ref := "fnref-" + escape(id)
+	if fn.refs > 1 {
+		ref = fmt.Sprintf("%s-%d", ref, fn.refs)
+	}
+	fmt.Fprintf(b, "<sup id=\"%s\"><a href=\"#fn-%s\">%d</a></sup>", ref, escape(id), num)
+	n = j + 1
+	return
+}
+
+// closingBracket returns the index of the bracket closing the one at i.
+func closingBracket(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			ticks := runLength(s, j, '`')
+			if k := closingCode(s, j+ticks, ticks); k >= 0 {
+				j = k + ticks - 1
+			} else {
+				j += ticks - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// linkDestination parses `(url "title")` at i in s.
+func linkDestination(s string, i int) (url, title string, n int) {
+	if i >= len(s) || s[i] != '(' {
+		return
+	}
+	j := i + 1
+	for j < len(s) && isSpace(s[j]) {
+		j++
+	}
+	if j < len(s) && s[j] == '<' {
+		k := strings.IndexByte(s[j:], '>')
+		if k < 0 {
+			return
+		}
+		url = s[j+1 : j+k]
+		j += k + 1
+	} else {
+		start := j
+		depth := 0
+	loop:
+		for ; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				j++
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					break loop
+				}
+				depth--
+			case ' ', '\t', '\n':
+				break loop
+			}
+		}
+		if j > len(s) {
+			return
+		}
+		url = s[start:j]
+	}
+	for j < len(s) && isSpace(s[j]) {
+		j++
+	}
+	if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+		quote := s[j]
+		k := strings.IndexByte(s[j+1:], quote)
+		if k < 0 {
+			return
+		}
+		title = s[j+1 : j+1+k]
+		j += k + 2
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+	}
+	if j >= len(s) || s[j] != ')' {
+		url, title = "", ""
+		return
+	}
+	n = j + 1 - i
+	return
+}
+
+// link renders a link or an image whose text starts with the bracket at i.
+func (r *renderer) link(b *bytes.Buffer, s string, i int, image bool) (n int) {
+	j := closingBracket(s, i)
+	if j < 0 {
+		return
+	}
+	url, title, m := linkDestination(s, j+1)
+	if m == 0 {
+		return
+	}
+	text := s[i+1 : j]
+	titleAttr := ""
+	if title != "" {
+		titleAttr = fmt.Sprintf(" title=\"%s\"", escape(title))
+	}
+	if image {
+		alt := rTag.ReplaceAllString(r.inline(text), "")
+		fmt.Fprintf(b, "<img src=\"%s\" alt=\"%s\"%s>", escape(url), alt, titleAttr)
+	} else {
+		r.inLink++
+		content := r.inline(text)
+		r.inLink--
+		fmt.Fprintf(b, "<a href=\"%s\"%s>%s</a>", escape(url), titleAttr, content)
+	}
+	n = j + 1 + m - i
+	return
+}
+
+// angle renders an autolink or raw HTML starting with `<`.
+func (r *renderer) angle(b *bytes.Buffer, s string, i int) (n int) {
+	if m := rAutolink.FindStringSubmatch(s[i:]); m != nil {
+		fmt.Fprintf(b, "<a href=\"%s\">%s</a>", escape(m[1]), escape(m[1]))
+		n = len(m[0])
+		return
+	}
+	if m := rEmail.FindStringSubmatch(s[i:]); m != nil {
+		fmt.Fprintf(b, "<a href=\"mailto:%s\">%s</a>", escape(m[1]), escape(m[1]))
+		n = len(m[0])
+		return
+	}
+	if m := rInlineHTML.FindString(s[i:]); m != "" {
+		b.WriteString(m)
+		n = len(m)
+	}
+	return
+}
+
+// bareURL links a URL written without any notation.
+func (r *renderer) bareURL(b *bytes.Buffer, s string, i int) (n int) {
+	if r.inLink > 0 || (i > 0 && isAlnum(s[i-1])) {
+		return
+	}
+	url := rBareURL.FindString(s[i:])
+	if url == "" {
+		return
+	}
+	for len(url) > 0 {
+		last := url[len(url)-1]
+		if strings.IndexByte(".,:;!?*_~'\"", last) >= 0 {
+			url = url[:len(url)-1]
+			continue
+		}
+		if last == ')' && strings.Count(url, "(") < strings.Count(url, ")") {
+			url = url[:len(url)-1]
+			continue
+		}
+		break
+	}
+	fmt.Fprintf(b, "<a href=\"%s\">%s</a>", escape(url), escape(url))
+	n = len(url)
+	return
+}
+
+// emphasis renders the text between `*`, `_` or `~~`.
+// The closing delimiter must have the same length as the opening one.
+func (r *renderer) emphasis(b *bytes.Buffer, s string, i int) (n int) {
+	c := s[i]
+	length := runLength(s, i, c)
+	if i+length >= len(s) || isSpace(s[i+length]) {
+		return
+	}
+	if c == '_' && i > 0 && isAlnum(s[i-1]) {
+		return
+	}
+	if c == '~' && length != 2 {
+		return
+	}
+	if length > 3 {
+		return
+	}
+
+	for j := i + length; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			ticks := runLength(s, j, '`')
+			if k := closingCode(s, j+ticks, ticks); k >= 0 {
+				j = k + ticks - 1
+			} else {
+				j += ticks - 1
+			}
+			continue
+		case c:
+		default:
+			continue
+		}
+		m := runLength(s, j, c)
+		if m != length || isSpace(s[j-1]) || (c == '_' && j+m < len(s) && isAlnum(s[j+m])) {
+			j += m - 1
+			continue
+		}
+		inner := r.inline(s[i+length : j])
+		switch {
+		case c == '~':
+			fmt.Fprintf(b, "<del>%s</del>", inner)
+		case length == 1:
+			fmt.Fprintf(b, "<em>%s</em>", inner)
+		case length == 2:
+			fmt.Fprintf(b, "<strong>%s</strong>", inner)
+		default:
+			fmt.Fprintf(b, "<em><strong>%s</strong></em>", inner)
+		}
+		n = j + m - i
+		return
+	}
+	b.WriteString(s[i : i+length])
+	n = length
+	return
+}
@@ -0,0 +1,666 @@
+// Package markdown renders the bodies of posts written in Qiita flavored markdown into HTML
+// without the API of Qiita.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	rFence       = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+	rNote        = regexp.MustCompile(`^ {0,3}:::note(?:[ \t]+(info|warn|alert))?[ \t]*$`)
+	rNoteEnd     = regexp.MustCompile(`^ {0,3}:::[ \t]*$`)
+	rMath        = regexp.MustCompile(`^ {0,3}\$\$[ \t]*$`)
+	rHeading     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	rSetext1     = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	rSetext2     = regexp.MustCompile(`^ {0,3}-+[ \t]*$`)
+	rRule        = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	rQuote       = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	rBullet      = regexp.MustCompile(`^( {0,3})([-+*])(?:([ \t]+)(.*))?$`)
+	rOrdered     = regexp.MustCompile(`^( {0,3})(\d{1,9})([.)])(?:([ \t]+)(.*))?$`)
+	rTask        = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	rDelimiter   = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	rHTMLBlock   = regexp.MustCompile(`^ {0,3}(?:<!--|<(?:/?)([A-Za-z][A-Za-z0-9]*)(?:[ \t>/]|$))`)
+	rFootnoteDef = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:[ \t]?(.*)$`)
+	rTag         = regexp.MustCompile(`<[^>]*>`)
+)
+
+// blockTags are the names of the tags which start blocks of raw HTML.
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true,
+	"dialog": true, "div": true, "dl": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "iframe": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"script": true, "section": true, "style": true, "summary": true, "table": true,
+	"tbody": true, "td": true, "tfoot": true, "th": true, "thead": true, "tr": true, "ul": true,
+}
+
+// Render renders the body of a post written in Qiita flavored markdown into HTML.
+// In addition to the basic markdown, it supports these notations of Qiita:
+// code blocks with `lang:filename`, `:::note` blocks, math, footnotes, tables and Mermaid diagrams.
+func Render(src string) (html string) {
+	r := newRenderer()
+	lines := r.collectFootnotes(splitLines(src))
+	var b bytes.Buffer
+	r.blocks(&b, lines, false)
+	r.footnoteList(&b)
+	html = b.String()
+	return
+}
+
+type footnote struct {
+	text string
+	refs int
+}
+
+type renderer struct {
+	footnotes map[string]*footnote
+	order     []string // 参照された順の脚注のID
+	slugs     map[string]int
+	inLink    int // リンクのテキストの中ではリンクを作らない
+}
+
+func newRenderer() *renderer {
+	return &renderer{
+		footnotes: make(map[string]*footnote),
+		slugs:     make(map[string]int),
+	}
+}
+
+func splitLines(src string) (lines []string) {
+	src = strings.Replace(src, "\r\n", "\n", -1)
+	src = strings.Replace(src, "\r", "\n", -1)
+	for _, line := range strings.Split(src, "\n") {
+		lines = append(lines, expandTabs(line))
+	}
+	return
+}
+
+// expandTabs replaces the tabs in the indent of line with spaces up to the next tab stop.
+func expandTabs(line string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			b.WriteByte(' ')
+		case '\t':
+			b.WriteString(strings.Repeat(" ", 4-b.Len()%4))
+		default:
+			b.WriteString(line[i:])
+			return b.String()
+		}
+	}
+	return b.String()
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// collectFootnotes removes the definitions of footnotes from lines and records them.
+// The lines indented after a definition continue it.
+func (r *renderer) collectFootnotes(lines []string) (rest []string) {
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if fence != "" {
+			if isClosingFence(line, fence) {
+				fence = ""
+			}
+			rest = append(rest, line)
+			continue
+		}
+		if m := rFence.FindStringSubmatch(line); m != nil {
+			fence = m[2]
+			rest = append(rest, line)
+			continue
+		}
+		m := rFootnoteDef.FindStringSubmatch(line)
+		if m == nil {
+			rest = append(rest, line)
+			continue
+		}
+		text := []string{m[2]}
+		for i+1 < len(lines) && !isBlank(lines[i+1]) && indentOf(lines[i+1]) >= 2 {
+			i++
+			text = append(text, strings.TrimSpace(lines[i]))
+		}
+		if _, ok := r.footnotes[m[1]]; !ok {
+			r.footnotes[m[1]] = &footnote{text: strings.Join(text, "\n")}
+		}
+	}
+	return
+}
+
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return indentOf(line) <= 3 &&
+		strings.HasPrefix(trimmed, fence) &&
+		strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// blocks renders lines as blocks.
+// In a tight list, paragraphs are rendered without p tags,
+// and inline reports whether lines are rendered as just such a paragraph.
+func (r *renderer) blocks(w *bytes.Buffer, lines []string, tight bool) (inline bool) {
+	n := 0
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			i++
+			continue
+		}
+		n++
+		inline = false
+		switch {
+		case rFence.MatchString(line):
+			i = r.fenced(w, lines, i)
+		case rMath.MatchString(line):
+			i = r.math(w, lines, i)
+		case rNote.MatchString(line):
+			i = r.note(w, lines, i)
+		case rHeading.MatchString(line):
+			m := rHeading.FindStringSubmatch(line)
+			r.heading(w, len(m[1]), m[2])
+			i++
+		case rRule.MatchString(line):
+			w.WriteString("<hr>\n")
+			i++
+		case rQuote.MatchString(line):
+			i = r.quote(w, lines, i)
+		case isListItem(line):
+			i = r.list(w, lines, i)
+		case isTableStart(lines, i):
+			i = r.table(w, lines, i)
+		case isHTMLBlock(line):
+			i = r.html(w, lines, i)
+		case indentOf(line) >= 4:
+			i = r.indentedCode(w, lines, i)
+		default:
+			i, inline = r.paragraph(w, lines, i, tight)
+		}
+	}
+	inline = inline && n == 1
+	return
+}
+
+// fenced renders a fenced code block.
+// The info string is the language optionally followed by `:filename`.
+// The blocks of mermaid and math are left for the scripts to render them.
+func (r *renderer) fenced(w *bytes.Buffer, lines []string, i int) int {
+	m := rFence.FindStringSubmatch(lines[i])
+	indent, fence, info := len(m[1]), m[2], m[3]
+	var code []string
+	i++
+	for ; i < len(lines); i++ {
+		if isClosingFence(lines[i], fence) {
+			i++
+			break
+		}
+		line := lines[i]
+		n := indentOf(line)
+		if n > indent {
+			n = indent
+		}
+		code = append(code, line[n:])
+	}
+	text := escape(strings.Join(code, "\n"))
+	if len(code) > 0 {
+		text += "\n"
+	}
+
+	lang, filename := info, ""
+	if j := strings.Index(info, ":"); j >= 0 {
+		lang, filename = info[:j], info[j+1:]
+	}
+	switch strings.ToLower(lang) {
+	case "mermaid":
+		fmt.Fprintf(w, "<div class=\"mermaid\">%s</div>\n", text)
+		return i
+	case "math":
+		fmt.Fprintf(w, "<div class=\"math\">\\[%s\\]</div>\n", text)
+		return i
+	}
+	fmt.Fprintf(w, "<div class=\"code-frame\" data-lang=\"%s\">", escape(lang))
+	if filename != "" {
+		fmt.Fprintf(w, "<div class=\"code-lang\"><span class=\"bold\">%s</span></div>", escape(filename))
+	}
+	fmt.Fprintf(w, "<div class=\"highlight\"><pre><code>%s</code></pre></div></div>\n", text)
+	return i
+}
+
+// math renders a block of math between `$$` lines.
+func (r *renderer) math(w *bytes.Buffer, lines []string, i int) int {
+	var tex []string
+	i++
+	for ; i < len(lines); i++ {
+		if rMath.MatchString(lines[i]) {
+			i++
+			break
+		}
+		tex = append(tex, lines[i])
+	}
+	fmt.Fprintf(w, "<div class=\"math\">\\[%s\\]</div>\n", escape(strings.Join(tex, "\n")))
+	return i
+}
+
+// note renders a `:::note` block with the type info, warn or alert.
+func (r *renderer) note(w *bytes.Buffer, lines []string, i int) int {
+	kind := rNote.FindStringSubmatch(lines[i])[1]
+	if kind == "" {
+		kind = "info"
+	}
+	start := i + 1
+	depth := 1
+	fence := ""
+	end := len(lines)
+	for i = start; i < len(lines); i++ {
+		line := lines[i]
+		if fence != "" {
+			if isClosingFence(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if m := rFence.FindStringSubmatch(line); m != nil {
+			fence = m[2]
+			continue
+		}
+		if rNote.MatchString(line) {
+			depth++
+			continue
+		}
+		if rNoteEnd.MatchString(line) {
+			depth--
+			if depth == 0 {
+				end = i
+				break
+			}
+		}
+	}
+	fmt.Fprintf(w, "<div class=\"note %s\">\n", kind)
+	r.blocks(w, lines[start:end], false)
+	w.WriteString("</div>\n")
+	if end < len(lines) {
+		end++
+	}
+	return end
+}
+
+func (r *renderer) heading(w *bytes.Buffer, level int, text string) {
+	content := r.inline(strings.TrimSpace(text))
+	fmt.Fprintf(w, "<h%d id=\"%s\">%s</h%d>\n", level, r.slug(content), content, level)
+}
+
+// slug makes the ID of a heading from the rendered content.
+// The same IDs are numbered to be unique.
+func (r *renderer) slug(content string) (slug string) {
+	text := strings.ToLower(html.UnescapeString(rTag.ReplaceAllString(content, "")))
+	var b bytes.Buffer
+	for _, c := range text {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-':
+			b.WriteRune(c)
+		case unicode.IsSpace(c):
+			b.WriteByte('-')
+		}
+	}
+	slug = b.String()
+	n := r.slugs[slug]
+	r.slugs[slug] = n + 1
+	if n > 0 {
+		slug = fmt.Sprintf("%s-%d", slug, n)
+	}
+	return
+}
+
+func (r *renderer) quote(w *bytes.Buffer, lines []string, i int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		m := rQuote.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		inner = append(inner, m[1])
+	}
+	w.WriteString("<blockquote>\n")
+	r.blocks(w, inner, false)
+	w.WriteString("</blockquote>\n")
+	return i
+}
+
+// listItem is the marker of an item of a list.
+type listItem struct {
+	ordered bool
+	marker  string // 箇条書きの記号か番号の後の記号
+	start   string // 番号付きリストの番号
+	indent  int    // 内容のインデント
+	content string
+}
+
+func matchListItem(line string) (item listItem, ok bool) {
+	if m := rBullet.FindStringSubmatch(line); m != nil {
+		item = listItem{marker: m[2], content: m[4]}
+		item.indent = listIndent(len(m[1])+len(m[2]), m[3])
+		ok = true
+		return
+	}
+	if m := rOrdered.FindStringSubmatch(line); m != nil {
+		item = listItem{ordered: true, marker: m[3], start: m[2], content: m[5]}
+		item.indent = listIndent(len(m[1])+len(m[2])+len(m[3]), m[4])
+		ok = true
+	}
+	return
+}
+
+// listIndent returns the indent of the content of an item.
+// The spaces after the marker more than 4 are taken as a part of the content.
+func listIndent(marker int, spaces string) int {
+	if len(spaces) == 0 || len(spaces) > 4 {
+		return marker + 1
+	}
+	return marker + len(spaces)
+}
+
+func isListItem(line string) bool {
+	_, ok := matchListItem(line)
+	return ok && !rRule.MatchString(line)
+}
+
+func (item listItem) sameList(other listItem) bool {
+	return item.ordered == other.ordered && item.marker == other.marker
+}
+
+func (r *renderer) list(w *bytes.Buffer, lines []string, i int) int {
+	first, _ := matchListItem(lines[i])
+	var items [][]string
+	loose := false
+	for i < len(lines) {
+		item, ok := matchListItem(lines[i])
+		if !ok || !item.sameList(first) || rRule.MatchString(lines[i]) {
+			break
+		}
+		content := []string{item.content}
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				j := i
+				for j < len(lines) && isBlank(lines[j]) {
+					j++
+				}
+				if j < len(lines) && indentOf(lines[j]) >= item.indent {
+					for ; i < j; i++ {
+						content = append(content, "")
+					}
+					loose = true
+					continue
+				}
+				break
+			}
+			if indentOf(line) >= item.indent {
+				content = append(content, line[item.indent:])
+				i++
+				continue
+			}
+			if isListItem(line) || startsBlock(line) {
+				break
+			}
+			// A lazy continuation of the paragraph.
+			content = append(content, strings.TrimLeft(line, " "))
+			i++
+		}
+		items = append(items, content)
+
+		j := i
+		for j < len(lines) && isBlank(lines[j]) {
+			j++
+		}
+		if j > i && j < len(lines) {
+			if next, ok := matchListItem(lines[j]); ok && next.sameList(first) {
+				loose = true
+				i = j
+			}
+		}
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && strings.TrimLeft(first.start, "0") != "1" {
+		fmt.Fprintf(w, "<ol start=\"%s\">\n", strings.TrimLeft(first.start, "0"))
+	} else {
+		fmt.Fprintf(w, "<%s>\n", tag)
+	}
+	for _, content := range items {
+		if m := rTask.FindStringSubmatch(content[0]); m != nil {
+			checked := ""
+			if m[1] != " " {
+				checked = " checked"
+			}
+			fmt.Fprintf(w, "<li class=\"task-list-item\"><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled%s>", checked)
+			content[0] = content[0][len(m[0]):]
+		} else {
+			w.WriteString("<li>")
+		}
+		var b bytes.Buffer
+		inner := ""
+		if r.blocks(&b, content, !loose) {
+			inner = strings.TrimSuffix(b.String(), "\n")
+		} else if b.Len() > 0 {
+			inner = "\n" + b.String()
+		}
+		w.WriteString(inner)
+		w.WriteString("</li>\n")
+	}
+	fmt.Fprintf(w, "</%s>\n", tag)
+	return i
+}
+
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || !rDelimiter.MatchString(lines[i+1]) {
+		return false
+	}
+	return len(splitRow(lines[i])) == len(splitRow(lines[i+1]))
+}
+
+// splitRow splits a row of a table into the cells.
+// The escaped pipes and the pipes in code spans don't split the cells.
+func splitRow(line string) (cells []string) {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+	var cell bytes.Buffer
+	code := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+			continue
+		case c == '`':
+			code = !code
+		case c == '|' && !code:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(c)
+	}
+	cells = append(cells, strings.TrimSpace(cell.String()))
+	return
+}
+
+func (r *renderer) table(w *bytes.Buffer, lines []string, i int) int {
+	header := splitRow(lines[i])
+	var aligns []string
+	for _, cell := range splitRow(lines[i+1]) {
+		left := strings.HasPrefix(cell, ":")
+		right := strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns = append(aligns, "center")
+		case left:
+			aligns = append(aligns, "left")
+		case right:
+			aligns = append(aligns, "right")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+	row := func(tag string, cells []string) {
+		w.WriteString("<tr>\n")
+		for j, align := range aligns {
+			cell := ""
+			if j < len(cells) {
+				cell = cells[j]
+			}
+			if align != "" {
+				fmt.Fprintf(w, "<%s style=\"text-align: %s\">%s</%s>\n", tag, align, r.inline(cell), tag)
+				continue
+			}
+			fmt.Fprintf(w, "<%s>%s</%s>\n", tag, r.inline(cell), tag)
+		}
+		w.WriteString("</tr>\n")
+	}
+
+	w.WriteString("<table>\n<thead>\n")
+	row("th", header)
+	w.WriteString("</thead>\n")
+	i += 2
+	body := false
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) || startsBlock(line) {
+			break
+		}
+		if !body {
+			w.WriteString("<tbody>\n")
+			body = true
+		}
+		row("td", splitRow(line))
+	}
+	if body {
+		w.WriteString("</tbody>\n")
+	}
+	w.WriteString("</table>\n")
+	return i
+}
+
+func isHTMLBlock(line string) bool {
+	m := rHTMLBlock.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	return m[1] == "" || blockTags[strings.ToLower(m[1])]
+}
+
+// html writes a block of raw HTML as it is until a blank line.
+func (r *renderer) html(w *bytes.Buffer, lines []string, i int) int {
+	for ; i < len(lines) && !isBlank(lines[i]); i++ {
+		w.WriteString(lines[i])
+		w.WriteString("\n")
+	}
+	return i
+}
+
+func (r *renderer) indentedCode(w *bytes.Buffer, lines []string, i int) int {
+	var code []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			code = append(code, "")
+			continue
+		}
+		if indentOf(line) < 4 {
+			break
+		}
+		code = append(code, line[4:])
+	}
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+		i--
+	}
+	fmt.Fprintf(w, "<pre><code>%s\n</code></pre>\n", escape(strings.Join(code, "\n")))
+	return i
+}
+
+// startsBlock reports whether line starts a block which interrupts a paragraph.
+func startsBlock(line string) bool {
+	if rFence.MatchString(line) || rMath.MatchString(line) || rNote.MatchString(line) ||
+		rHeading.MatchString(line) || rRule.MatchString(line) || rQuote.MatchString(line) ||
+		isHTMLBlock(line) {
+		return true
+	}
+	item, ok := matchListItem(line)
+	if !ok || strings.TrimSpace(item.content) == "" {
+		return false
+	}
+	return !item.ordered || strings.TrimLeft(item.start, "0") == "1"
+}
+
+// paragraph renders a paragraph, or a heading underlined with `=` or `-`.
+// The line breaks in a paragraph are kept as Qiita does.
+// inline reports whether the paragraph is rendered without p tags.
+func (r *renderer) paragraph(w *bytes.Buffer, lines []string, i int, tight bool) (next int, inline bool) {
+	var text []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if len(text) > 0 {
+			if rSetext1.MatchString(line) {
+				r.heading(w, 1, strings.Join(text, "\n"))
+				next = i + 1
+				return
+			}
+			if rSetext2.MatchString(line) {
+				r.heading(w, 2, strings.Join(text, "\n"))
+				next = i + 1
+				return
+			}
+			if isBlank(line) || startsBlock(line) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(line, " "))
+	}
+	content := r.inline(strings.TrimRight(strings.Join(text, "\n"), " "))
+	next = i
+	if tight {
+		w.WriteString(content)
+		w.WriteString("\n")
+		inline = true
+		return
+	}
+	fmt.Fprintf(w, "<p>%s</p>\n", content)
+	return
+}
+
+// footnoteList renders the footnotes in the order they are referenced.
+func (r *renderer) footnoteList(w *bytes.Buffer) {
+	if len(r.order) == 0 {
+		return
+	}
+	w.WriteString("<div class=\"footnotes\">\n<hr>\n<ol>\n")
+	for _, id := range r.order {
+		fmt.Fprintf(
+			w,
+			"<li id=\"fn-%s\">\n<p>%s <a href=\"#fnref-%s\">&#8617;</a></p>\n</li>\n",
+			escape(id), r.inline(r.footnotes[id].text), escape(id),
+		)
+	}
+	w.WriteString("</ol>\n</div>\n")
+}
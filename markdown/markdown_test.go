@@ -0,0 +1,173 @@
+package markdown_test
+
+import (
+	"testing"
+
+	"github.com/minodisk/qiitactl/markdown"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func testRender(t *testing.T, src, expected string) {
+	actual := markdown.Render(src)
+	if actual != expected {
+		t.Errorf("wrong html:\n%s", testutil.Diff(expected, actual))
+	}
+}
+
+func TestRenderParagraph(t *testing.T) {
+	testRender(t, `Hello, **world**
+with *emphasis*, ~~deleted~~ and `+"`<code>`"+`.
+
+1 < 2 & 3 > 2`, `<p>Hello, <strong>world</strong><br>
+with <em>emphasis</em>, <del>deleted</del> and <code>&lt;code&gt;</code>.</p>
+<p>1 &lt; 2 &amp; 3 &gt; 2</p>
+`)
+}
+
+func TestRenderHeading(t *testing.T) {
+	testRender(t, `# Title
+## Sub Title
+## Sub Title
+Setext
+---`, `<h1 id="title">Title</h1>
+<h2 id="sub-title">Sub Title</h2>
+<h2 id="sub-title-1">Sub Title</h2>
+<h2 id="setext">Setext</h2>
+`)
+}
+
+func TestRenderCodeWithFilename(t *testing.T) {
+	testRender(t, "```ruby:app.rb\nputs 'Hello'\n```\n\n```\n<a>\n```", `<div class="code-frame" data-lang="ruby"><div class="code-lang"><span class="bold">app.rb</span></div><div class="highlight"><pre><code>puts 'Hello'
+</code></pre></div></div>
+<div class="code-frame" data-lang=""><div class="highlight"><pre><code>&lt;a&gt;
+</code></pre></div></div>
+`)
+}
+
+func TestRenderNote(t *testing.T) {
+	testRender(t, `:::note warn
+Be **careful**.
+:::
+:::note
+Info
+:::`, `<div class="note warn">
+<p>Be <strong>careful</strong>.</p>
+</div>
+<div class="note info">
+<p>Info</p>
+</div>
+`)
+}
+
+func TestRenderMath(t *testing.T) {
+	testRender(t, "$$\na < b\n$$\n\n```math\nx^2\n```\n\nInline $e^{i\\pi}$ costs $5 and $6.", `<div class="math">\[a &lt; b\]</div>
+<div class="math">\[x^2
+\]</div>
+<p>Inline <span class="math">\(e^{i\pi}\)</span> costs $5 and $6.</p>
+`)
+}
+
+func TestRenderMermaid(t *testing.T) {
+	testRender(t, "```mermaid\ngraph TD;\n  A-->B;\n```", `<div class="mermaid">graph TD;
+  A--&gt;B;
+</div>
+`)
+}
+
+func TestRenderFootnotes(t *testing.T) {
+	testRender(t, `Foo[^1] and bar[^note].
+
+[^note]: The note.
+[^1]: The first.`, `<p>Foo<sup id="fnref-1"><a href="#fn-1">1</a></sup> and bar<sup id="fnref-note"><a href="#fn-note">2</a></sup>.</p>
+<div class="footnotes">
+<hr>
+<ol>
+<li id="fn-1">
+<p>The first. <a href="#fnref-1">&#8617;</a></p>
+</li>
+<li id="fn-note">
+<p>The note. <a href="#fnref-note">&#8617;</a></p>
+</li>
+</ol>
+</div>
+`)
+}
+
+func TestRenderTable(t *testing.T) {
+	testRender(t, `| Left | Center | Right |
+|:-----|:------:|------:|
+| a    | `+"`b|c`"+` | **d** |`, `<table>
+<thead>
+<tr>
+<th style="text-align: left">Left</th>
+<th style="text-align: center">Center</th>
+<th style="text-align: right">Right</th>
+</tr>
+</thead>
+<tbody>
+<tr>
+<td style="text-align: left">a</td>
+<td style="text-align: center"><code>b|c</code></td>
+<td style="text-align: right"><strong>d</strong></td>
+</tr>
+</tbody>
+</table>
+`)
+}
+
+func TestRenderList(t *testing.T) {
+	testRender(t, `- a
+- b
+  1. c
+  2. d
+- [x] done
+- [ ] todo
+
+3. three
+
+   more`, `<ul>
+<li>a</li>
+<li>
+b
+<ol>
+<li>c</li>
+<li>d</li>
+</ol>
+</li>
+<li class="task-list-item"><input type="checkbox" class="task-list-item-checkbox" disabled checked>done</li>
+<li class="task-list-item"><input type="checkbox" class="task-list-item-checkbox" disabled>todo</li>
+</ul>
+<ol start="3">
+<li>
+<p>three</p>
+<p>more</p>
+</li>
+</ol>
+`)
+}
+
+func TestRenderLinks(t *testing.T) {
+	testRender(t, `[Qiita](https://qiita.com "Qiita") ![logo](./logo.png) <https://example.com>
+See https://example.com/a_(b). [https://example.com](https://example.com)`, `<p><a href="https://qiita.com" title="Qiita">Qiita</a> <img src="./logo.png" alt="logo"> <a href="https://example.com">https://example.com</a><br>
+See <a href="https://example.com/a_(b)">https://example.com/a_(b)</a>. <a href="https://example.com">https://example.com</a></p>
+`)
+}
+
+func TestRenderQuoteAndHTML(t *testing.T) {
+	testRender(t, `> quoted
+> **text**
+
+<details><summary>More</summary>
+hidden
+</details>
+
+***`, `<blockquote>
+<p>quoted<br>
+<strong>text</strong></p>
+</blockquote>
+<details><summary>More</summary>
+hidden
+</details>
+<hr>
+`)
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DirBase is the directory where the snapshots of posts are saved when they are fetched.
+// Local and remote changes are merged on the snapshots.
+var DirBase = filepath.Join(DirWorkspace, "base")
+
+func basePath(id string) string {
+	return filepath.Join(DirBase, id+".md")
+}
+
+// LoadBase loads the snapshot of the post with id.
+// nil is returned when no snapshot is saved.
+func LoadBase(id string) (base *Post, err error) {
+	post, err := NewPostWithFile(basePath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	base = &post
+	return
+}
+
+// SaveBase saves the post as the snapshot which later changes are merged on.
+func (post Post) SaveBase() (err error) {
+	if post.ID == "" {
+		err = EmptyIDError{}
+		return
+	}
+	post.Path = basePath(post.ID)
+	post.Format = FormatComment
+	post.Meta.raw = ""
+	err = os.MkdirAll(DirBase, 0755)
+	if err != nil {
+		return
+	}
+	f, err := os.Create(post.Path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	err = post.Encode(f)
+	return
+}
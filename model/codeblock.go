@@ -0,0 +1,137 @@
+package model
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Info strings of the code blocks run by exec and of the blocks where their output is recorded.
+const (
+	FilenameRun = "run"
+	LangOutput  = "output"
+)
+
+// CodeBlock is a fenced code block in the body of a post.
+type CodeBlock struct {
+	Lang     string // 情報文字列の言語
+	Filename string // 情報文字列の:以降のファイル名
+	Code     string // コード
+	Start    int    // 開きフェンスの本文中の行 (0から)
+	End      int    // 閉じフェンスの本文中の行 (0から)
+}
+
+// CodeBlocks returns the closed fenced code blocks in body.
+// The info string is split into the language and the filename like `go:main.go`.
+func CodeBlocks(body string) (blocks []CodeBlock) {
+	lines := strings.Split(body, "\n")
+	fence := ""
+	var block CodeBlock
+	for i, line := range lines {
+		next, ok := nextFence(fence, line)
+		if !ok {
+			continue
+		}
+		if fence == "" {
+			info := strings.TrimSpace(rCodeFence.FindStringSubmatch(line)[2])
+			block = CodeBlock{
+				Lang:  info,
+				Start: i,
+			}
+			if j := strings.Index(info, ":"); j >= 0 {
+				block.Lang, block.Filename = info[:j], info[j+1:]
+			}
+		} else {
+			block.End = i
+			block.Code = strings.Join(lines[block.Start+1:i], "\n")
+			blocks = append(blocks, block)
+		}
+		fence = next
+	}
+	return
+}
+
+var rExtension = regexp.MustCompile(`^[A-Za-z0-9_+\-]+$`)
+
+// Extension returns the extension of the file for the code in the block.
+// It is `.txt` for the blocks without the language.
+func (block CodeBlock) Extension() string {
+	var exts []string
+	for ext, lang := range includeLangs {
+		if lang == block.Lang {
+			exts = append(exts, ext)
+		}
+	}
+	if len(exts) > 0 {
+		sort.Strings(exts)
+		return exts[0]
+	}
+	if !rExtension.MatchString(block.Lang) {
+		return ".txt"
+	}
+	return "." + block.Lang
+}
+
+// codeFence returns the shortest fence of backticks which can enclose code.
+func codeFence(code string) (fence string) {
+	fence = "```"
+	for _, backticks := range rBackticks.FindAllString(code, -1) {
+		if len(backticks) >= len(fence) {
+			fence = backticks + "`"
+		}
+	}
+	return
+}
+
+// outputBlock returns the `output` block which follows block in body only with blank lines between them.
+func outputBlock(lines []string, blocks []CodeBlock, block CodeBlock) (output CodeBlock, ok bool) {
+	i := block.End + 1
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	for _, b := range blocks {
+		if b.Start == i && b.Lang == LangOutput && b.Filename == "" {
+			output, ok = b, true
+			return
+		}
+	}
+	return
+}
+
+// RecordedOutput returns the output recorded in the `output` block following block in body.
+func RecordedOutput(body string, block CodeBlock) (output string, ok bool) {
+	b, ok := outputBlock(strings.Split(body, "\n"), CodeBlocks(body), block)
+	output = b.Code
+	return
+}
+
+// RecordOutputs returns body where the outputs are recorded in the `output` blocks
+// following the code blocks which start at the lines of the keys.
+// The output block is refreshed when it already follows the code block, or inserted after it.
+func RecordOutputs(body string, outputs map[int]string) string {
+	lines := strings.Split(body, "\n")
+	blocks := CodeBlocks(body)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		output, ok := outputs[block.Start]
+		if !ok {
+			continue
+		}
+		output = strings.TrimRight(output, "\n")
+		fence := codeFence(output)
+		recorded := []string{fence + LangOutput}
+		if output != "" {
+			recorded = append(recorded, strings.Split(output, "\n")...)
+		}
+		recorded = append(recorded, fence)
+
+		start, end := block.End+1, block.End+1
+		if b, ok := outputBlock(lines, blocks, block); ok {
+			start, end = b.Start, b.End+1
+		} else {
+			recorded = append([]string{""}, recorded...)
+		}
+		lines = append(lines[:start], append(recorded, lines[end:]...)...)
+	}
+	return strings.Join(lines, "\n")
+}
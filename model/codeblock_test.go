@@ -0,0 +1,57 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	blocks := model.CodeBlocks("text\n```go:main.go\npackage main\n\nfunc main() {}\n```\n\n~~~sh\necho ```\n~~~\n\n```\nunclosed")
+	if len(blocks) != 2 {
+		t.Fatalf("wrong number of blocks: %v", blocks)
+	}
+	if b := blocks[0]; b.Lang != "go" || b.Filename != "main.go" || b.Code != "package main\n\nfunc main() {}" || b.Start != 1 || b.End != 5 {
+		t.Errorf("wrong block: %+v", b)
+	}
+	if b := blocks[1]; b.Lang != "sh" || b.Filename != "" || b.Code != "echo ```" || b.Start != 7 || b.End != 9 {
+		t.Errorf("wrong block: %+v", b)
+	}
+}
+
+func TestRecordOutputs(t *testing.T) {
+	body := "```sh:run\necho a\n```\n\n```output\nold\n```\n\n```sh:run\necho b\n```\ntext\n\n```sh:run\necho c\n```"
+	blocks := model.CodeBlocks(body)
+	if output, ok := model.RecordedOutput(body, blocks[0]); !ok || output != "old" {
+		t.Errorf("wrong recorded output: %t %s", ok, output)
+	}
+	if _, ok := model.RecordedOutput(body, blocks[2]); ok {
+		t.Error("the block shouldn't have the recorded output")
+	}
+
+	recorded := model.RecordOutputs(body, map[int]string{
+		blocks[0].Start: "a\n",
+		blocks[2].Start: "```\nb\n",
+	})
+	expected := "```sh:run\necho a\n```\n\n```output\na\n```\n\n```sh:run\necho b\n```\n\n````output\n```\nb\n````\ntext\n\n```sh:run\necho c\n```"
+	if recorded != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, recorded))
+	}
+}
+
+func TestCodeBlockExtension(t *testing.T) {
+	for lang, ext := range map[string]string{
+		"go":         ".go",
+		"javascript": ".js",
+		"yaml":       ".yaml",
+		"sh":         ".sh",
+		"elixir":     ".elixir",
+		"":           ".txt",
+		"c#":         ".txt",
+	} {
+		if e := (model.CodeBlock{Lang: lang}).Extension(); e != ext {
+			t.Errorf("wrong extension of %s: %s", lang, e)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DirWorkspace is the directory where qiitactl stores data of the workspace.
+const DirWorkspace = ".qiitactl"
+
+// ConfigPath is the path of the configuration file of the workspace.
+var ConfigPath = filepath.Join(DirWorkspace, "config.yml")
+
+// Config is configuration of the workspace.
+type Config struct {
+	Format           Format              `yaml:"format"`            // 新しく書き出すファイルの形式
+	PathTemplate     string              `yaml:"path_template"`     // 新しく書き出すファイルのパスのテンプレート
+	DefaultTemplates map[string]string   `yaml:"default_templates"` // チームのID (Qiitaはmine) ごとの既定のテンプレートの名前
+	Lint             map[string]LintRule `yaml:"lint"`              // lintのルールごとの設定
+}
+
+// LintRule is configuration of a rule of lint.
+type LintRule struct {
+	Severity string `yaml:"severity"` // error, warning, offのいずれか
+	Min      int    `yaml:"min"`      // 数や長さの下限
+	Max      int    `yaml:"max"`      // 数や長さの上限
+}
+
+// LoadConfig loads the configuration of the workspace from ConfigPath.
+// The default configuration is returned when the file doesn't exist.
+func LoadConfig() (config Config, err error) {
+	b, err := ioutil.ReadFile(ConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	err = yaml.Unmarshal(b, &config)
+	if err != nil {
+		return
+	}
+	if config.Format != "" {
+		err = config.Format.Validate()
+	}
+	return
+}
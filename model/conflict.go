@@ -0,0 +1,181 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+)
+
+// ConflictsPath is the path of the file which records the files with unresolved conflicts.
+var ConflictsPath = filepath.Join(DirWorkspace, "conflicts.json")
+
+var rConflictMarker = regexp.MustCompile(`(?m)^(<<<<<<< local|>>>>>>> remote)$`)
+
+// CheckConflict fetches the post from Qiita and compares its updated_at with the one recorded in the post.
+// ConflictError is returned with the remote post when the post is updated in Qiita after it is fetched.
+func (post Post) CheckConflict(client api.Client) (remote Post, err error) {
+	if post.Draft {
+		err = DraftError{
+			Path: post.Path,
+		}
+		return
+	}
+	if post.ID == "" {
+		err = EmptyIDError{}
+		return
+	}
+	remote, err = FetchPost(client, post.Team, post.ID)
+	if err != nil {
+		return
+	}
+	if remote.UpdatedAt.Equal(post.UpdatedAt.Time) {
+		return
+	}
+	err = ConflictError{
+		Path:   post.Path,
+		Local:  post,
+		Remote: remote,
+	}
+	return
+}
+
+// HasConflictMarkers reports whether the body of the post has conflict markers left by Merge.
+func (post Post) HasConflictMarkers() bool {
+	return rConflictMarker.MatchString(post.Body)
+}
+
+// CheckResolved returns UnresolvedError when the post has conflict markers
+// or the file isn't marked as resolved yet.
+func (post Post) CheckResolved() (err error) {
+	conflicts, err := LoadConflicts()
+	if err != nil {
+		return
+	}
+	if post.HasConflictMarkers() || conflicts.Has(post.Path) {
+		err = UnresolvedError{
+			Path: post.Path,
+		}
+	}
+	return
+}
+
+// text returns the title and body as they are written in the file.
+func (post Post) text() string {
+	return fmt.Sprintf("# %s\n\n%s", post.Title, strings.TrimSpace(post.Body))
+}
+
+// Conflicts is the set of the paths of files with unresolved conflicts.
+type Conflicts map[string]bool
+
+// LoadConflicts loads the set from ConflictsPath.
+// An empty set is returned when the file doesn't exist.
+func LoadConflicts() (conflicts Conflicts, err error) {
+	conflicts = make(Conflicts)
+	b, err := ioutil.ReadFile(ConflictsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	var paths []string
+	err = json.Unmarshal(b, &paths)
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		conflicts[path] = true
+	}
+	return
+}
+
+// AddConflict records the file at path as the one with unresolved conflicts.
+func AddConflict(path string) (err error) {
+	conflicts, err := LoadConflicts()
+	if err != nil {
+		return
+	}
+	conflicts[filepath.Clean(path)] = true
+	err = conflicts.Save()
+	return
+}
+
+// RenameConflict moves the record of unresolved conflicts in the file at from to the file at to.
+func RenameConflict(from, to string) (err error) {
+	conflicts, err := LoadConflicts()
+	if err != nil {
+		return
+	}
+	if !conflicts.Has(from) {
+		return
+	}
+	delete(conflicts, filepath.Clean(from))
+	conflicts[filepath.Clean(to)] = true
+	err = conflicts.Save()
+	return
+}
+
+// Has reports whether the file at path has unresolved conflicts.
+func (conflicts Conflicts) Has(path string) bool {
+	return conflicts[filepath.Clean(path)]
+}
+
+// Paths returns the sorted paths of the files with unresolved conflicts.
+func (conflicts Conflicts) Paths() (paths []string) {
+	paths = []string{}
+	for path := range conflicts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return
+}
+
+// Save writes the set to ConflictsPath.
+func (conflicts Conflicts) Save() (err error) {
+	err = os.MkdirAll(filepath.Dir(ConflictsPath), 0755)
+	if err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(conflicts.Paths(), "", "  ")
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(ConflictsPath, b, 0644)
+	return
+}
+
+// ConflictError occurs when a post is updated in Qiita after it is fetched.
+type ConflictError struct {
+	Path   string
+	Local  Post
+	Remote Post
+}
+
+func (err ConflictError) Error() (msg string) {
+	msg = fmt.Sprintf(
+		"conflict: %s is based on the post updated at %s, but it was updated at %s in Qiita:\n%s",
+		err.Path,
+		err.Local.UpdatedAt.Local().Format(time.RFC3339),
+		err.Remote.UpdatedAt.Local().Format(time.RFC3339),
+		Diff(err.Local.text(), err.Remote.text()),
+	)
+	return
+}
+
+// UnresolvedError occurs when conflicts are left in a post.
+type UnresolvedError struct {
+	Path string
+}
+
+func (err UnresolvedError) Error() (msg string) {
+	msg = fmt.Sprintf("%s has conflicts: resolve them and run `qiitactl resolve %s`", err.Path, err.Path)
+	return
+}
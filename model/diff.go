@@ -0,0 +1,173 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines shown around changes in unified diffs.
+const diffContext = 3
+
+// Diff returns the lines which differ between src and dst,
+// prefixed with "- " for the lines only in src and "+ " for the lines only in dst.
+func Diff(src, dst string) (s string) {
+	var lines []string
+	for _, d := range diffLines(src, dst) {
+		var prefix string
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			prefix = "- "
+		case diffmatchpatch.DiffInsert:
+			prefix = "+ "
+		default:
+			continue
+		}
+		for _, line := range splitLines(d.Text) {
+			lines = append(lines, prefix+line)
+		}
+	}
+	s = strings.Join(lines, "\n")
+	return
+}
+
+func diffLines(src, dst string) (diffs []diffmatchpatch.Diff) {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(src+"\n", dst+"\n")
+	diffs = dmp.DiffMain(a, b, false)
+	diffs = dmp.DiffCharsToLines(diffs, lines)
+	return
+}
+
+func splitLines(text string) []string {
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+func diffLineOps(src, dst string) (ops []diffLine) {
+	for _, d := range diffLines(src, dst) {
+		for _, line := range splitLines(d.Text) {
+			ops = append(ops, diffLine{d.Type, line})
+		}
+	}
+	return
+}
+
+// UnifiedDiff returns the unified diff from src to dst with srcName and dstName as the labels.
+// An empty string is returned when they are the same.
+func UnifiedDiff(srcName, dstName, src, dst string) (s string) {
+	ops := diffLineOps(src, dst)
+	// srcLines[i] and dstLines[i] are the numbers of lines before ops[i] in src and dst.
+	srcLines := make([]int, len(ops)+1)
+	dstLines := make([]int, len(ops)+1)
+	for i, op := range ops {
+		srcLines[i+1] = srcLines[i]
+		dstLines[i+1] = dstLines[i]
+		if op.op != diffmatchpatch.DiffInsert {
+			srcLines[i+1]++
+		}
+		if op.op != diffmatchpatch.DiffDelete {
+			dstLines[i+1]++
+		}
+	}
+
+	var lines []string
+	prevEnd := 0
+	i := 0
+	for {
+		for i < len(ops) && ops[i].op == diffmatchpatch.DiffEqual {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := i - diffContext
+		if start < prevEnd {
+			start = prevEnd
+		}
+		end := i
+		for {
+			for end < len(ops) && ops[end].op != diffmatchpatch.DiffEqual {
+				end++
+			}
+			j := end
+			for j < len(ops) && ops[j].op == diffmatchpatch.DiffEqual {
+				j++
+			}
+			if j < len(ops) && j-end <= 2*diffContext {
+				end = j
+				continue
+			}
+			if end+diffContext < j {
+				j = end + diffContext
+			}
+			end = j
+			break
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"@@ -%s +%s @@",
+			hunkRange(srcLines[start], srcLines[end]),
+			hunkRange(dstLines[start], dstLines[end]),
+		))
+		for _, op := range ops[start:end] {
+			switch op.op {
+			case diffmatchpatch.DiffDelete:
+				lines = append(lines, "-"+op.text)
+			case diffmatchpatch.DiffInsert:
+				lines = append(lines, "+"+op.text)
+			default:
+				lines = append(lines, " "+op.text)
+			}
+		}
+		prevEnd = end
+		i = end
+	}
+	if len(lines) == 0 {
+		return
+	}
+	lines = append([]string{"--- " + srcName, "+++ " + dstName}, lines...)
+	s = strings.Join(lines, "\n")
+	return
+}
+
+func hunkRange(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, end-start)
+}
+
+// DiffStat returns the numbers of lines inserted and deleted from src to dst.
+func DiffStat(src, dst string) (insertions, deletions int) {
+	for _, op := range diffLineOps(src, dst) {
+		switch op.op {
+		case diffmatchpatch.DiffInsert:
+			insertions++
+		case diffmatchpatch.DiffDelete:
+			deletions++
+		}
+	}
+	return
+}
+
+// DiffText returns the title, the meta which can be updated and the body of the post
+// as lines to be compared.
+func (post Post) DiffText() string {
+	lines := []string{
+		"title: " + post.Title,
+		fmt.Sprintf("private: %t", post.Private),
+		fmt.Sprintf("coediting: %t", post.Coediting),
+		"tags:",
+	}
+	for _, tag := range post.Tags {
+		lines = append(lines, "- "+strings.TrimSpace(tag.Name+" "+strings.Join(tag.Versions, " ")))
+	}
+	lines = append(lines, "", strings.TrimSpace(post.Body))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,51 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestDiff(t *testing.T) {
+	actual := model.Diff("a\nb\nc", "a\nB\nc\nd")
+	expected := `- b
++ B
++ d`
+	if actual != expected {
+		t.Errorf("wrong diff:\n%s", testutil.Diff(expected, actual))
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	src := strings.Join([]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15"}, "\n")
+	dst := strings.Join([]string{"1", "two", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16"}, "\n")
+	actual := model.UnifiedDiff("a", "b", src, dst)
+	expected := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -13,3 +13,4 @@
+ 13
+ 14
+ 15
++16`
+	if actual != expected {
+		t.Errorf("wrong diff:\n%s", testutil.Diff(expected, actual))
+	}
+
+	if model.UnifiedDiff("a", "b", src, src) != "" {
+		t.Errorf("same text shouldn't have diff")
+	}
+
+	insertions, deletions := model.DiffStat(src, dst)
+	if insertions != 2 || deletions != 1 {
+		t.Errorf("wrong stat: %d insertions, %d deletions", insertions, deletions)
+	}
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/minodisk/qiitactl/api"
+)
+
+// Engagement is the reactions of readers to a post in Qiita.
+type Engagement struct {
+	LikesCount     int  `json:"likes_count"`      // いいねの数
+	CommentsCount  int  `json:"comments_count"`   // コメントの数
+	StocksCount    int  `json:"stocks_count"`     // ストックされた数
+	PageViewsCount *int `json:"page_views_count"` // 閲覧数 (自分の投稿でのみ取得できる)
+}
+
+// FetchPostEngagement fetches a post from Qiita with the reactions to it.
+func FetchPostEngagement(client api.Client, team *Team, id string) (post Post, engagement Engagement, err error) {
+	subDomain := ""
+	if team != nil {
+		subDomain = team.ID
+	}
+	body, _, err := client.Get(subDomain, fmt.Sprintf("/items/%s", id), nil)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(body, &post)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(body, &engagement)
+	if err != nil {
+		return
+	}
+	post.Team = team
+	return
+}
@@ -0,0 +1,23 @@
+package model
+
+import "fmt"
+
+// Format is a layout of a markdown file of a post.
+type Format string
+
+const (
+	// FormatComment puts meta in an HTML comment and the title in a heading.
+	FormatComment Format = "comment"
+	// FormatFrontMatter puts meta and the title in YAML front matter.
+	FormatFrontMatter Format = "front_matter"
+)
+
+// Validate validates the format.
+func (format Format) Validate() (err error) {
+	switch format {
+	case FormatComment, FormatFrontMatter:
+		return
+	}
+	err = fmt.Errorf("unknown format: %s", format)
+	return
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var rHeaderKey = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s#'"-][^:]*):(?:\s|$)`)
+
+// headerBlock is a top-level key with its value, or a comment or an empty line, in a header.
+type headerBlock struct {
+	key   string
+	lines []string
+}
+
+func (b headerBlock) String() string {
+	return strings.Join(b.lines, "\n")
+}
+
+func (b headerBlock) value() (v interface{}) {
+	var m map[string]interface{}
+	err := yaml.Unmarshal([]byte(b.String()), &m)
+	if err != nil {
+		return
+	}
+	v = m[b.key]
+	return
+}
+
+func splitHeader(s string) (blocks []headerBlock) {
+	if s == "" {
+		return
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if m := rHeaderKey.FindStringSubmatch(line); m != nil {
+			blocks = append(blocks, headerBlock{
+				key:   strings.Trim(m[1], `"'`),
+				lines: []string{line},
+			})
+			continue
+		}
+		continued := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "-")
+		if continued {
+			// Comments and empty lines between a key and its value belong to the key.
+			k := len(blocks) - 1
+			for k >= 0 && blocks[k].key == "" {
+				k--
+			}
+			if k >= 0 {
+				for _, b := range blocks[k+1:] {
+					blocks[k].lines = append(blocks[k].lines, b.lines...)
+				}
+				blocks = blocks[:k+1]
+				blocks[k].lines = append(blocks[k].lines, line)
+				continue
+			}
+		}
+		blocks = append(blocks, headerBlock{
+			lines: []string{line},
+		})
+	}
+	return
+}
+
+// mergeHeader merges fresh, which is the encoded header, into raw, which is the header read from a file.
+// Comments and keys not in known are kept as they are in raw with their order.
+// Values of known keys are replaced with the ones in fresh unless they are unchanged,
+// and known keys missing in fresh are removed.
+func mergeHeader(raw, fresh string, known map[string]bool) (out string) {
+	freshBlocks := splitHeader(fresh)
+	freshByKey := make(map[string]headerBlock)
+	for _, b := range freshBlocks {
+		freshByKey[b.key] = b
+	}
+
+	var blocks []headerBlock
+	placed := make(map[string]bool)
+	for _, b := range splitHeader(raw) {
+		if b.key == "" {
+			blocks = append(blocks, b)
+			continue
+		}
+		f, ok := freshByKey[b.key]
+		if !ok {
+			if !known[b.key] {
+				blocks = append(blocks, b)
+			}
+			continue
+		}
+		placed[b.key] = true
+		if reflect.DeepEqual(b.value(), f.value()) {
+			blocks = append(blocks, b)
+		} else {
+			blocks = append(blocks, f)
+		}
+	}
+
+	// Insert the keys missing in raw after the key preceding them in fresh.
+	for i, f := range freshBlocks {
+		if placed[f.key] {
+			continue
+		}
+		at := 0
+		for j := i - 1; j >= 0 && at == 0; j-- {
+			for k, b := range blocks {
+				if b.key == freshBlocks[j].key {
+					at = k + 1
+					break
+				}
+			}
+		}
+		blocks = append(blocks[:at], append([]headerBlock{f}, blocks[at:]...)...)
+		placed[f.key] = true
+	}
+
+	lines := make([]string, len(blocks))
+	for i, b := range blocks {
+		lines[i] = b.String()
+	}
+	out = strings.Join(lines, "\n")
+	return
+}
@@ -0,0 +1,236 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndexPath is the path of the file which records the local files of posts.
+var IndexPath = filepath.Join(DirWorkspace, "index.json")
+
+// IgnoredDirs are the directories which aren't searched for posts.
+var IgnoredDirs = []string{
+	DirWorkspace,
+	"node_modules",
+	"vendor",
+}
+
+// indexVersion is the version of the entries in the index.
+// The entries of older versions are re-read from the files.
+const indexVersion = 1
+
+// IndexEntry is a record of a local file of a post.
+type IndexEntry struct {
+	ID        string    `json:"id"`         // 投稿の一意なID
+	URL       string    `json:"url"`        // 投稿のURL
+	Draft     bool      `json:"draft"`      // 下書きかどうか
+	Path      string    `json:"path"`       // ファイルのパス
+	Hash      string    `json:"hash"`       // ファイルの内容のハッシュ
+	UpdatedAt Time      `json:"updated_at"` // ファイルに記録されている投稿の更新日時
+	Team      string    `json:"team"`       // チームのID
+	ModTime   time.Time `json:"mod_time"`   // ファイルの更新日時
+	Size      int64     `json:"size"`       // ファイルのサイズ
+	Invalid   bool      `json:"invalid"`    // ファイルが投稿として読み込めないかどうか
+	Version   int       `json:"version"`    // エントリの形式のバージョン
+}
+
+// Index records the local files of posts with their paths as keys.
+type Index map[string]IndexEntry
+
+// LoadIndex loads the index from IndexPath.
+// An empty index is returned when the file doesn't exist.
+func LoadIndex() (index Index, err error) {
+	index = make(Index)
+	b, err := ioutil.ReadFile(IndexPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	err = json.Unmarshal(b, &index)
+	return
+}
+
+// indexMutex serializes updating the index, which is done by the workers of push at the same time.
+var indexMutex sync.Mutex
+
+// UpdateIndex loads the index, updates it with the files in current working directory and saves it.
+func UpdateIndex() (index Index, err error) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	index, err = LoadIndex()
+	if err != nil {
+		return
+	}
+	err = index.Update()
+	if err != nil {
+		return
+	}
+	err = index.Save()
+	return
+}
+
+// RebuildIndex makes the index from scratch and saves it.
+func RebuildIndex() (index Index, err error) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	index = make(Index)
+	err = index.Update()
+	if err != nil {
+		return
+	}
+	err = index.Save()
+	return
+}
+
+// Save writes the index to IndexPath.
+// The file is replaced at once, so that the index being written is never loaded.
+func (index Index) Save() (err error) {
+	err = os.MkdirAll(filepath.Dir(IndexPath), 0755)
+	if err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(filepath.Dir(IndexPath), "index-*.json")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	err = os.Rename(f.Name(), IndexPath)
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return
+}
+
+// Update walks current working directory and updates the index.
+// Only the files modified since the last update are parsed,
+// and the entries of removed files are deleted.
+func (index Index) Update() (err error) {
+	found := make(map[string]bool)
+	err = filepath.Walk(".", func(p string, i os.FileInfo, e error) (err error) {
+		if e != nil {
+			err = e
+			return
+		}
+		if i.IsDir() {
+			if p != "." && isIgnoredDir(i.Name()) {
+				err = filepath.SkipDir
+			}
+			return
+		}
+		if filepath.Ext(p) != ".md" {
+			return
+		}
+		found[p] = true
+
+		if entry, ok := index[p]; ok && entry.fresh(i) {
+			return
+		}
+		index[p] = newIndexEntry(p, i)
+		return
+	})
+	if err != nil {
+		return
+	}
+	for p := range index {
+		if !found[p] {
+			delete(index, p)
+		}
+	}
+	return
+}
+
+// Lookup returns the entry of the file at path when the file isn't modified since it is indexed.
+func (index Index) Lookup(path string) (entry IndexEntry, ok bool) {
+	entry, ok = index[filepath.Clean(path)]
+	if !ok {
+		return
+	}
+	i, err := os.Stat(path)
+	if err != nil {
+		ok = false
+		return
+	}
+	ok = entry.fresh(i)
+	return
+}
+
+// Paths returns the paths of the files of published posts with the IDs as keys.
+func (index Index) Paths() (paths map[string]string) {
+	paths = make(map[string]string)
+	for p, entry := range index {
+		if entry.Invalid || entry.ID == "" {
+			continue
+		}
+		paths[entry.ID] = p
+	}
+	return
+}
+
+func (entry IndexEntry) fresh(i os.FileInfo) bool {
+	return entry.Version == indexVersion && entry.ModTime.Equal(i.ModTime()) && entry.Size == i.Size()
+}
+
+func newIndexEntry(path string, i os.FileInfo) (entry IndexEntry) {
+	entry = IndexEntry{
+		Path:    path,
+		ModTime: i.ModTime(),
+		Size:    i.Size(),
+		Invalid: true,
+		Version: indexVersion,
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var post Post
+	err = post.Decode(b)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(b)
+	entry.ID = post.ID
+	entry.URL = post.URL
+	entry.Draft = post.Draft
+	entry.Hash = hex.EncodeToString(sum[:])
+	entry.UpdatedAt = post.UpdatedAt
+	if post.Team != nil {
+		entry.Team = post.Team.ID
+	}
+	entry.Invalid = false
+	return
+}
+
+func isIgnoredDir(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, dir := range IgnoredDirs {
+		if name == dir {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package model_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+const indexedPost = `<!--
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+private: false
+coediting: false
+tags:
+- Go
+team: null
+-->
+
+# Example Title
+
+## Example body`
+
+func writeIndexedPost(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateIndex(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	writeIndexedPost(t, "mine/2000/01/01/post.md", indexedPost)
+	writeIndexedPost(t, "node_modules/foo/post.md", indexedPost)
+	writeIndexedPost(t, "mine/2000/01/02/post.md", "# Not a post")
+
+	index, err := model.UpdateIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := index.Paths()
+	if len(paths) != 1 || paths["4bd431809afb1bb99e4f"] != "mine/2000/01/01/post.md" {
+		t.Errorf("wrong paths: %v", paths)
+	}
+	entry, ok := index.Lookup("mine/2000/01/01/post.md")
+	if !ok {
+		t.Fatal("entry should be found")
+	}
+	if entry.Hash == "" || entry.UpdatedAt.IsZero() {
+		t.Errorf("wrong entry: %+v", entry)
+	}
+
+	loaded, err := model.LoadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(index) {
+		t.Errorf("index should be saved: %v", loaded)
+	}
+
+	err = os.Rename("mine/2000/01/01/post.md", "mine/2000/01/02/renamed.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err = model.UpdateIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = index.Paths()
+	if paths["4bd431809afb1bb99e4f"] != "mine/2000/01/02/renamed.md" {
+		t.Errorf("moved file should be indexed: %v", paths)
+	}
+	if _, ok := index.Lookup("mine/2000/01/01/post.md"); ok {
+		t.Errorf("removed file should be dropped from index")
+	}
+}
+
+func TestUpdateIndexConcurrently(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	for i := 0; i < 10; i++ {
+		writeIndexedPost(t, fmt.Sprintf("mine/2000/01/01/post-%d.md", i), indexedPost)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := model.UpdateIndex(); err != nil {
+					errs <- err
+				}
+				if _, err := model.LoadIndex(); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("the index should be updated and loaded at the same time: %s", err)
+	}
+
+	files, err := ioutil.ReadDir(model.DirWorkspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != filepath.Base(model.IndexPath) {
+		t.Errorf("only the index should be left: %v", files)
+	}
+}
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	rLink           = regexp.MustCompile(`(\]\()([^)\s]+)((?:\s+"[^"]*")?\))`)
+	rLinkDefinition = regexp.MustCompile(`^( {0,3}\[[^\]]+\]:[ \t]*)(\S+)(.*)$`)
+	rPostURL        = regexp.MustCompile(`^https?://(?:[a-z0-9\-]+\.)?qiita\.com/[^/]+/items/([0-9a-f]{20})(#.*)?$`)
+	rLinkScheme     = regexp.MustCompile(`^(?:[a-zA-Z][a-zA-Z0-9+.\-]*:|//|/|#)`)
+	linkEscaper     = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29")
+)
+
+// rewriteLinks calls fn with the target of every link and link definition in body
+// outside of code blocks and code spans, and replaces the target with the returned one.
+func rewriteLinks(body string, fn func(link string) string) string {
+	return mapOutsideCode(body, func(line string) string {
+		if m := rLinkDefinition.FindStringSubmatch(line); m != nil {
+			return m[1] + fn(m[2]) + m[3]
+		}
+		return mapOutsideCodeSpans(line, func(text string) string {
+			return rLink.ReplaceAllStringFunc(text, func(s string) string {
+				m := rLink.FindStringSubmatch(s)
+				return m[1] + fn(m[2]) + m[3]
+			})
+		})
+	})
+}
+
+// postLink returns the path of the file of a post which link refers to relatively from dir,
+// and the fragment of the link.
+func postLink(link, dir string) (path, fragment string, ok bool) {
+	if rLinkScheme.MatchString(link) {
+		return
+	}
+	p := link
+	if i := strings.IndexByte(p, '#'); i >= 0 {
+		p, fragment = p[:i], p[i:]
+	}
+	p, err := url.PathUnescape(p)
+	if err != nil || filepath.Ext(p) != ".md" {
+		return
+	}
+	path = filepath.Join(dir, filepath.FromSlash(p))
+	ok = true
+	return
+}
+
+// ResolveLinks returns the body whose relative links to the files of other posts
+// are replaced with the URLs of the posts in Qiita.
+// The files are looked up in the index, which is updated only when a file isn't indexed or is modified.
+// LinkError is returned with the links which can't be resolved, such as the links to drafts
+// and to the files which aren't posts in the workspace, while the other links are resolved in the body.
+func (post Post) ResolveLinks() (body string, err error) {
+	var index Index
+	updated := false
+	lookup := func(path string) (entry IndexEntry, ok bool, err error) {
+		if index == nil {
+			index, err = LoadIndex()
+			if err != nil {
+				return
+			}
+		}
+		entry, ok = index.Lookup(path)
+		if ok || updated {
+			return
+		}
+		index, err = UpdateIndex()
+		if err != nil {
+			return
+		}
+		updated = true
+		entry, ok = index.Lookup(path)
+		return
+	}
+
+	var invalid []string
+	dir := filepath.Dir(post.Path)
+	body = rewriteLinks(post.Body, func(link string) string {
+		path, fragment, ok := postLink(link, dir)
+		if !ok || err != nil {
+			return link
+		}
+		var target IndexEntry
+		target, ok, err = lookup(path)
+		switch {
+		case err != nil:
+		case !ok || target.Invalid:
+			invalid = append(invalid, fmt.Sprintf("%s: not a post in the workspace", link))
+		case target.ID == "" || target.Draft:
+			invalid = append(invalid, fmt.Sprintf("%s: not published yet", link))
+		case target.URL == "":
+			invalid = append(invalid, fmt.Sprintf("%s: no URL is recorded", link))
+		default:
+			return target.URL + fragment
+		}
+		return link
+	})
+	if err != nil {
+		return
+	}
+	if len(invalid) > 0 {
+		err = LinkError{
+			Path:  post.Path,
+			Links: invalid,
+		}
+	}
+	return
+}
+
+// RelativizeLinks replaces the URLs of the posts in Qiita in the body with the relative paths to their files.
+// paths maps the IDs of the posts to the paths of their files.
+// changed reports whether any link is replaced.
+func (post *Post) RelativizeLinks(paths map[string]string) (changed bool) {
+	dir := filepath.Dir(post.Path)
+	post.Body = rewriteLinks(post.Body, func(link string) string {
+		m := rPostURL.FindStringSubmatch(link)
+		if m == nil {
+			return link
+		}
+		path, ok := paths[m[1]]
+		if !ok {
+			return link
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return link
+		}
+		changed = true
+		return linkEscaper.Replace(filepath.ToSlash(rel)) + m[2]
+	})
+	return
+}
+
+// LinkError occurs when relative links in a post refer to files which aren't published posts.
+type LinkError struct {
+	Path  string
+	Links []string
+}
+
+func (err LinkError) Error() (msg string) {
+	msgs := []string{fmt.Sprintf("%s has links which can't be resolved into posts in Qiita:", err.Path)}
+	for _, link := range err.Links {
+		msgs = append(msgs, fmt.Sprintf("- %s", link))
+	}
+	msg = strings.Join(msgs, "\n")
+	return
+}
@@ -0,0 +1,113 @@
+package model_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func writeLinkedPosts(t *testing.T) {
+	files := map[string]string{
+		"mine/2024/05/01/part-1.md":   "<!--\ntags:\n- Go\n-->\n\n# Part 1\n\nbody",
+		"mine/2024/05/02/part-2.md":   "<!--\nid: 00000000000000000002\nurl: https://qiita.com/foo/items/00000000000000000002\ntags:\n- Go\n-->\n\n# Part 2\n\nbody",
+		"mine/2024/05/03/draft.md":    "<!--\ndraft: true\ntags:\n- Go\n-->\n\n# Draft\n\nbody",
+		"mine/2024/05/04/a post.md":   "<!--\nid: 00000000000000000004\nurl: https://qiita.com/foo/items/00000000000000000004\ntags:\n- Go\n-->\n\n# A Post\n\nbody",
+		"mine/2024/05/05/not-post.md": "no header",
+		"node_modules/published.md":   "<!--\nid: 00000000000000000007\nurl: https://qiita.com/foo/items/00000000000000000007\ntags:\n- Go\n-->\n\n# Vendored\n\nbody",
+	}
+	for path, content := range files {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPostResolveLinks(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+	writeLinkedPosts(t)
+
+	post := model.Post{
+		Body: `See [part 2](../02/part-2.md#usage "Part 2") and [a post](../04/a%20post.md).
+[Qiita](https://qiita.com/) and the image ![](./image.md.png) are kept.
+` + "`[code](../02/part-2.md)`" + `
+
+[ref]: ../02/part-2.md
+
+` + "```\n[code](../02/part-2.md)\n```",
+	}
+	post.Path = "mine/2024/05/01/part-1.md"
+	body, err := post.ResolveLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `See [part 2](https://qiita.com/foo/items/00000000000000000002#usage "Part 2") and [a post](https://qiita.com/foo/items/00000000000000000004).
+[Qiita](https://qiita.com/) and the image ![](./image.md.png) are kept.
+` + "`[code](../02/part-2.md)`" + `
+
+[ref]: https://qiita.com/foo/items/00000000000000000002
+
+` + "```\n[code](../02/part-2.md)\n```"
+	if body != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, body))
+	}
+}
+
+func TestPostResolveLinksWithUnresolvableLinks(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+	writeLinkedPosts(t)
+
+	post := model.Post{
+		Body: "[draft](../03/draft.md), [new](part-1.md), [missing](../06/missing.md), [broken](../05/not-post.md), [ignored](../../../../node_modules/published.md) and [part 2](../02/part-2.md)",
+	}
+	post.Path = "mine/2024/05/01/part-1.md"
+	body, err := post.ResolveLinks()
+	e, ok := err.(model.LinkError)
+	if !ok {
+		t.Fatalf("LinkError should be returned: %v", err)
+	}
+	if len(e.Links) != 5 ||
+		e.Links[0] != "../03/draft.md: not published yet" ||
+		e.Links[1] != "part-1.md: not published yet" ||
+		e.Links[2] != "../06/missing.md: not a post in the workspace" ||
+		e.Links[3] != "../05/not-post.md: not a post in the workspace" ||
+		e.Links[4] != "../../../../node_modules/published.md: not a post in the workspace" {
+		t.Errorf("wrong links: %v", e.Links)
+	}
+	if !strings.HasSuffix(body, "[part 2](https://qiita.com/foo/items/00000000000000000002)") {
+		t.Errorf("the other links should be resolved: %s", body)
+	}
+}
+
+func TestPostRelativizeLinks(t *testing.T) {
+	post := model.Post{
+		Body: `See [part 2](https://qiita.com/foo/items/00000000000000000002#usage), [a post](https://increments.qiita.com/foo/items/00000000000000000004)
+and [others](https://qiita.com/bar/items/00000000000000000009).
+` + "`https://qiita.com/foo/items/00000000000000000002`",
+	}
+	post.Path = filepath.Join("mine", "2024", "05", "01", "part-1.md")
+	changed := post.RelativizeLinks(map[string]string{
+		"00000000000000000002": filepath.Join("mine", "2024", "05", "02", "part-2.md"),
+		"00000000000000000004": filepath.Join("mine", "2024", "05", "04", "a post.md"),
+	})
+	if !changed {
+		t.Error("the links should be changed")
+	}
+	expected := `See [part 2](../02/part-2.md#usage), [a post](../04/a%20post.md)
+and [others](https://qiita.com/bar/items/00000000000000000009).
+` + "`https://qiita.com/foo/items/00000000000000000002`"
+	if post.Body != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, post.Body))
+	}
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleLockAge is the age after which a lock file without a valid PID is taken as stale.
+const staleLockAge = time.Minute
+
+// Lock creates a lock file named name in the workspace,
+// so that the operation isn't run by multiple processes at the same time.
+// The lock left by a process which isn't running anymore is taken over.
+// Call unlock to release the lock.
+func Lock(name string) (unlock func() error, err error) {
+	err = os.MkdirAll(DirWorkspace, 0755)
+	if err != nil {
+		return
+	}
+	path := filepath.Join(DirWorkspace, fmt.Sprintf("%s.lock", name))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) && staleLock(path) {
+		os.Remove(path)
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	}
+	if err != nil {
+		if os.IsExist(err) {
+			err = LockedError{
+				Path: path,
+				PID:  lockPID(path),
+			}
+		}
+		return
+	}
+	_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	f.Close()
+	if err != nil {
+		os.Remove(path)
+		return
+	}
+	unlock = func() error {
+		return os.Remove(path)
+	}
+	return
+}
+
+// lockPID returns the PID written in the lock file at path, or 0 when it can't be read.
+func lockPID(path string) (pid int) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	pid, _ = strconv.Atoi(strings.TrimSpace(string(b)))
+	return
+}
+
+// staleLock reports whether the lock file at path is left by a process which isn't running.
+// The file without a valid PID, which may be being written now, is stale only when it is old.
+func staleLock(path string) bool {
+	if pid := lockPID(path); pid > 0 {
+		return !processAlive(pid)
+	}
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > staleLockAge
+}
+
+// LockedError occurs when the lock is held by another process.
+type LockedError struct {
+	Path string
+	PID  int
+}
+
+func (err LockedError) Error() (msg string) {
+	msg = fmt.Sprintf("locked by another process (pid %d): remove %s if no other qiitactl is running", err.PID, err.Path)
+	return
+}
@@ -0,0 +1,76 @@
+package model_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestLock(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	unlock, err := model.Lock("publish")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = model.Lock("publish")
+	if _, ok := err.(model.LockedError); !ok {
+		t.Fatalf("locked error should occur: %v", err)
+	}
+
+	err = unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err = model.Lock("publish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
+
+func TestLockTakesOverStaleLock(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll(model.DirWorkspace, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(model.DirWorkspace, "publish.lock")
+
+	// The process of the lock is running.
+	err = ioutil.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = model.Lock("publish")
+	if e, ok := err.(model.LockedError); !ok || e.PID != os.Getpid() {
+		t.Fatalf("locked error with the PID should occur: %v", err)
+	}
+
+	// The process of the lock has exited.
+	cmd := exec.Command("go", "version")
+	err = cmd.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path, []byte(fmt.Sprintf("%d\n", cmd.Process.Pid)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := model.Lock("publish")
+	if err != nil {
+		t.Fatalf("stale lock should be taken over: %v", err)
+	}
+	unlock()
+}
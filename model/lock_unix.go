@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package model
+
+import (
+	"syscall"
+)
+
+// processAlive reports whether the process with pid is running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"os"
+)
+
+// processAlive reports whether the process with pid is running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,270 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	markerLocal  = "<<<<<<< local"
+	markerSep    = "======="
+	markerRemote = ">>>>>>> remote"
+)
+
+// Merge puts the changes of the post since base on the remote post.
+// base is the snapshot of the post saved when it was fetched last time.
+// Without base, the title and meta of the post are kept and all the lines of the body which differ conflict.
+// The conflicting lines are left with conflict markers, and false is returned when any conflict is left.
+func (post *Post) Merge(base *Post, remote Post) (clean bool) {
+	local := *post
+	*post = remote
+	post.Path = local.Path
+	post.Format = local.Format
+	post.Meta.raw = local.Meta.raw
+	post.Draft = local.Draft
+	post.PublishAt = local.PublishAt
+	post.Assets = local.Assets
+	remoteBody := strings.TrimSpace(remote.Body)
+
+	if base == nil {
+		post.Title = local.Title
+		post.Tags = local.Tags
+		post.Private = local.Private
+		post.Coediting = local.Coediting
+		post.Slide = local.Slide
+		post.Body, clean = mergeLines(local.Body, remoteBody)
+		return
+	}
+
+	post.Private = mergeBool(base.Private, local.Private, remote.Private)
+	post.Coediting = mergeBool(base.Coediting, local.Coediting, remote.Coediting)
+	post.Slide = mergeBool(base.Slide, local.Slide, remote.Slide)
+	post.Tags = mergeTags(base.Tags, local.Tags, remote.Tags)
+	post.Body, clean = merge3(base.Body, local.Body, remoteBody)
+
+	switch {
+	case local.Title == remote.Title || remote.Title == base.Title:
+		post.Title = local.Title
+	case local.Title == base.Title:
+		post.Title = remote.Title
+	default:
+		// The title can't have conflict markers, so the conflict is put on the top of the body.
+		post.Title = local.Title
+		post.Body = strings.Join([]string{
+			markerLocal,
+			"# " + local.Title,
+			markerSep,
+			"# " + remote.Title,
+			markerRemote,
+			"",
+			post.Body,
+		}, "\n")
+		clean = false
+	}
+	return
+}
+
+func conflictLines(local, remote []string) (lines []string) {
+	lines = append(lines, markerLocal)
+	lines = append(lines, local...)
+	lines = append(lines, markerSep)
+	lines = append(lines, remote...)
+	lines = append(lines, markerRemote)
+	return
+}
+
+// mergeLines merges local and remote line by line.
+// The lines which differ are put between conflict markers.
+func mergeLines(local, remote string) (merged string, clean bool) {
+	clean = true
+	var lines, ours, theirs []string
+	flush := func() {
+		if len(ours) == 0 && len(theirs) == 0 {
+			return
+		}
+		clean = false
+		lines = append(lines, conflictLines(ours, theirs)...)
+		ours, theirs = nil, nil
+	}
+	for _, d := range diffLines(local, remote) {
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			ours = append(ours, splitLines(d.Text)...)
+		case diffmatchpatch.DiffInsert:
+			theirs = append(theirs, splitLines(d.Text)...)
+		default:
+			flush()
+			lines = append(lines, splitLines(d.Text)...)
+		}
+	}
+	flush()
+	merged = strings.Join(lines, "\n")
+	return
+}
+
+// hunk replaces the lines from start to end in base with lines.
+type hunk struct {
+	start int
+	end   int
+	lines []string
+}
+
+func hunks(base, other string) (hs []hunk) {
+	i := 0
+	var h *hunk
+	for _, d := range diffLines(base, other) {
+		lines := splitLines(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			if h != nil {
+				hs = append(hs, *h)
+				h = nil
+			}
+			i += len(lines)
+			continue
+		}
+		if h == nil {
+			h = &hunk{start: i, end: i}
+		}
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			i += len(lines)
+			h.end = i
+		case diffmatchpatch.DiffInsert:
+			h.lines = append(h.lines, lines...)
+		}
+	}
+	if h != nil {
+		hs = append(hs, *h)
+	}
+	return
+}
+
+// apply returns the lines from start to end in base with hs applied.
+func apply(base []string, hs []hunk, start, end int) (lines []string) {
+	i := start
+	for _, h := range hs {
+		lines = append(lines, base[i:h.start]...)
+		lines = append(lines, h.lines...)
+		i = h.end
+	}
+	lines = append(lines, base[i:end]...)
+	return
+}
+
+// merge3 merges the changes from base to local and from base to remote line by line.
+// The changes which touch the same lines are put between conflict markers unless they are the same.
+func merge3(base, local, remote string) (merged string, clean bool) {
+	clean = true
+	baseLines := strings.Split(base, "\n")
+	ls := hunks(base, local)
+	rs := hunks(base, remote)
+
+	var lines []string
+	pos := 0
+	for len(ls) > 0 || len(rs) > 0 {
+		var start int
+		switch {
+		case len(rs) == 0 || (len(ls) > 0 && ls[0].start <= rs[0].start):
+			start = ls[0].start
+		default:
+			start = rs[0].start
+		}
+		end := start
+		var lg, rg []hunk
+		for {
+			if len(ls) > 0 && ls[0].start <= end {
+				lg = append(lg, ls[0])
+				if ls[0].end > end {
+					end = ls[0].end
+				}
+				ls = ls[1:]
+				continue
+			}
+			if len(rs) > 0 && rs[0].start <= end {
+				rg = append(rg, rs[0])
+				if rs[0].end > end {
+					end = rs[0].end
+				}
+				rs = rs[1:]
+				continue
+			}
+			break
+		}
+
+		lines = append(lines, baseLines[pos:start]...)
+		ours := apply(baseLines, lg, start, end)
+		theirs := apply(baseLines, rg, start, end)
+		switch {
+		case len(rg) == 0:
+			lines = append(lines, ours...)
+		case len(lg) == 0:
+			lines = append(lines, theirs...)
+		case strings.Join(ours, "\n") == strings.Join(theirs, "\n"):
+			lines = append(lines, ours...)
+		default:
+			lines = append(lines, conflictLines(ours, theirs)...)
+			clean = false
+		}
+		pos = end
+	}
+	lines = append(lines, baseLines[pos:]...)
+	merged = strings.Join(lines, "\n")
+	return
+}
+
+func mergeBool(base, local, remote bool) bool {
+	if local != base {
+		return local
+	}
+	return remote
+}
+
+// mergeTags merges the tags added, removed or modified in local into remote.
+func mergeTags(base, local, remote Tags) (merged Tags) {
+	find := func(tags Tags, name string) (tag Tag, ok bool) {
+		for _, tag = range tags {
+			if tag.Name == name {
+				ok = true
+				return
+			}
+		}
+		return
+	}
+
+	for _, r := range remote {
+		b, inBase := find(base, r.Name)
+		l, inLocal := find(local, r.Name)
+		switch {
+		case inBase && !inLocal:
+			continue
+		case inLocal && (!inBase || !sameTag(l, b)):
+			merged = append(merged, l)
+		default:
+			merged = append(merged, r)
+		}
+	}
+	for _, l := range local {
+		if _, ok := find(base, l.Name); ok {
+			continue
+		}
+		if _, ok := find(remote, l.Name); ok {
+			continue
+		}
+		merged = append(merged, l)
+	}
+	return
+}
+
+func sameTag(a, b Tag) bool {
+	if a.Name != b.Name || len(a.Versions) != len(b.Versions) {
+		return false
+	}
+	for i := range a.Versions {
+		if a.Versions[i] != b.Versions[i] {
+			return false
+		}
+	}
+	return true
+}
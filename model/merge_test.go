@@ -0,0 +1,211 @@
+package model_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func TestPostMerge(t *testing.T) {
+	base := model.Post{
+		Title: "Title",
+		Body:  "# A\n\na\n\n# B\n\nb\n\n# C\n\nc",
+	}
+	base.Tags = model.Tags{{Name: "Go"}, {Name: "Ruby"}}
+
+	local := base
+	local.Body = "# A\n\na local\n\n# B\n\nb\n\n# C\n\nc"
+	local.Tags = model.Tags{{Name: "Go", Versions: []string{"1.6"}}, {Name: "Docker"}}
+	local.Private = true
+
+	remote := base
+	remote.Title = "Remote Title"
+	remote.Body = "# A\n\na\n\n# B\n\nb\n\n# C\n\nc remote\n"
+	remote.Tags = model.Tags{{Name: "Go"}, {Name: "Ruby"}, {Name: "Rails"}}
+
+	post := local
+	clean := post.Merge(&base, remote)
+	if !clean {
+		t.Fatalf("changes in different lines should be merged:\n%s", post.Body)
+	}
+	if post.Title != "Remote Title" {
+		t.Errorf("wrong title: %s", post.Title)
+	}
+	expected := "# A\n\na local\n\n# B\n\nb\n\n# C\n\nc remote"
+	if post.Body != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, post.Body))
+	}
+	tags := ""
+	for _, tag := range post.Tags {
+		tags += tag.Name + " "
+	}
+	if tags != "Go Rails Docker " || len(post.Tags[0].Versions) != 1 {
+		t.Errorf("wrong tags: %+v", post.Tags)
+	}
+	if !post.Private {
+		t.Errorf("local change of private should be kept")
+	}
+}
+
+func TestPostMergeWithConflict(t *testing.T) {
+	base := model.Post{
+		Title: "Title",
+		Body:  "# A\n\na\n\n# B\n\nb",
+	}
+	local := base
+	local.Title = "Local Title"
+	local.Body = "# A\n\na local\n\n# B\n\nb"
+	remote := base
+	remote.Title = "Remote Title"
+	remote.Body = "# A\n\na remote\n\n# B\n\nb"
+
+	post := local
+	clean := post.Merge(&base, remote)
+	if clean {
+		t.Fatal("conflict should be left")
+	}
+	if post.Title != "Local Title" {
+		t.Errorf("wrong title: %s", post.Title)
+	}
+	expected := `<<<<<<< local
+# Local Title
+=======
+# Remote Title
+>>>>>>> remote
+
+# A
+
+<<<<<<< local
+a local
+=======
+a remote
+>>>>>>> remote
+
+# B
+
+b`
+	if post.Body != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, post.Body))
+	}
+	if !post.HasConflictMarkers() {
+		t.Error("conflict markers should be found")
+	}
+}
+
+func TestPostMergeWithoutBase(t *testing.T) {
+	local := model.Post{
+		Title: "Local Title",
+		Body:  "# Head\n\nlocal\n\n# Tail",
+	}
+	remote := model.Post{
+		Title: "Remote Title",
+		Body:  "# Head\n\nremote\n\n# Tail\n",
+	}
+	post := local
+	clean := post.Merge(nil, remote)
+	if clean {
+		t.Error("conflict should be left")
+	}
+	if post.Title != "Local Title" {
+		t.Errorf("wrong title: %s", post.Title)
+	}
+	expected := `# Head
+
+<<<<<<< local
+local
+=======
+remote
+>>>>>>> remote
+
+# Tail`
+	if post.Body != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, post.Body))
+	}
+
+	post = model.Post{Body: "same"}
+	if !post.Merge(nil, model.Post{Body: "same"}) || post.HasConflictMarkers() {
+		t.Errorf("same body shouldn't conflict: %q", post.Body)
+	}
+}
+
+func TestPostSaveMerged(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	remote := model.NewPost("Example Title", nil, nil)
+	remote.ID = "4bd431809afb1bb99e4f"
+	remote.Tags = model.Tags{{Name: "Go"}}
+	remote.Body = "# A\n\na\n\n# B\n\nb"
+
+	post := remote
+	clean, err := post.SaveMerged(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !clean {
+		t.Fatal("first fetch shouldn't conflict")
+	}
+	path := post.Path
+
+	local, err := model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local.Body = "# A\n\na local\n\n# B\n\nb"
+	err = local.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post = remote
+	post.Body = "# A\n\na\n\n# B\n\nb remote"
+	clean, err = post.SaveMerged(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !clean {
+		t.Fatalf("changes in different lines should be merged:\n%s", post.Body)
+	}
+	merged, err := model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Body != "# A\n\na local\n\n# B\n\nb remote" {
+		t.Errorf("wrong body:\n%s", merged.Body)
+	}
+
+	post = remote
+	post.Body = "# A\n\na remote\n\n# B\n\nb remote"
+	clean, err = post.SaveMerged(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clean {
+		t.Fatal("conflict should be left")
+	}
+	conflicts, err := model.LoadConflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conflicts.Has(path) {
+		t.Errorf("conflict should be recorded: %v", conflicts)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err = model.NewPostWithFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := merged.CheckResolved(); err == nil {
+		t.Errorf("unresolved error should occur:\n%s", b)
+	}
+	_, err = os.Stat(".qiitactl/base/4bd431809afb1bb99e4f.md")
+	if err != nil {
+		t.Errorf("base should be saved: %s", err)
+	}
+}
@@ -3,33 +3,99 @@ package model
 import (
 	"bytes"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
 // Meta is meta data of post.
 type Meta struct {
-	ID        string `json:"id" yaml:"id"`                 // 投稿の一意なID
-	URL       string `json:"url" yaml:"url"`               // 投稿のURL
-	CreatedAt Time   `json:"created_at" yaml:"created_at"` // データが作成された日時
-	UpdatedAt Time   `json:"updated_at" yaml:"updated_at"` // データが最後に更新された日時
-	Private   bool   `json:"private" yaml:"private"`       // 限定共有状態かどうかを表すフラグ (Qiita:Teamでは無効)
-	Coediting bool   `json:"coediting" yaml:"coediting"`   // この投稿が共同更新状態かどうか (Qiita:Teamでのみ有効)
-	Tags      Tags   `json:"tags" yaml:"tags"`             // 投稿に付いたタグ一覧
-	Team      *Team  `json:"-"`                            // チーム
+	ID                  string            `json:"id" yaml:"id"`                                                           // 投稿の一意なID
+	URL                 string            `json:"url" yaml:"url"`                                                         // 投稿のURL
+	CreatedAt           Time              `json:"created_at" yaml:"created_at"`                                           // データが作成された日時
+	UpdatedAt           Time              `json:"updated_at" yaml:"updated_at"`                                           // データが最後に更新された日時
+	Private             bool              `json:"private" yaml:"private"`                                                 // 限定共有状態かどうかを表すフラグ (Qiita:Teamでは無効)
+	Coediting           bool              `json:"coediting" yaml:"coediting"`                                             // この投稿が共同更新状態かどうか (Qiita:Teamでのみ有効)
+	Slide               bool              `json:"slide" yaml:"slide,omitempty"`                                           // スライドモードが有効かどうか
+	OrganizationURLName *string           `json:"organization_url_name,omitempty" yaml:"organization_url_name,omitempty"` // 投稿を紐付けるOrganizationのURL名 (Qiita:Teamでは無効)
+	GroupURLName        *string           `json:"group_url_name,omitempty" yaml:"group_url_name,omitempty"`               // 投稿を公開するグループのURL名 (Qiita:Teamでのみ有効)
+	Tags                Tags              `json:"tags" yaml:"tags"`                                                       // 投稿に付いたタグ一覧
+	Draft               bool              `json:"-" yaml:"draft,omitempty"`                                               // まだ投稿していない下書きかどうか
+	PublishAt           *Time             `json:"-" yaml:"publish_at,omitempty"`                                          // 下書きを投稿する日時
+	Assets              map[string]string `json:"-" yaml:"assets,omitempty"`                                              // ローカルに保存した画像のパスと元のURLの対応
+	Team                *Team             `json:"-"`                                                                      // チーム
+
+	raw string // ファイルから読み込んだままのメタデータ
 }
 
+// metaKeys is the set of keys which Meta can decode.
+var metaKeys = func() (keys map[string]bool) {
+	keys = make(map[string]bool)
+	t := reflect.TypeOf(Meta{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		keys[name] = true
+	}
+	return
+}()
+
+// headerKeys is the set of keys which qiitactl writes in the header of a file.
+var headerKeys = func() (keys map[string]bool) {
+	keys = map[string]bool{
+		"title": true,
+	}
+	for key := range metaKeys {
+		keys[key] = true
+	}
+	return
+}()
+
 // Encode marshals meta as YAML.
+// When meta is decoded from a file, unknown keys and comments in it are kept.
 func (meta Meta) Encode() (out string) {
 	o, err := yaml.Marshal(meta)
 	if err != nil {
 		fmt.Println(err)
 	}
 	out = string(bytes.TrimSpace(o))
+	if meta.raw != "" {
+		out = mergeHeader(meta.raw, out, headerKeys)
+	}
 	return
 }
 
 // Decode unmarshals encoded meta.
+// Keys which Meta doesn't know are written back with comments when encoding.
 func (meta *Meta) Decode(s string) (err error) {
-	return yaml.Unmarshal([]byte(s), meta)
+	err = yaml.Unmarshal([]byte(s), meta)
+	if err != nil {
+		return
+	}
+	meta.raw = s
+	return
+}
+
+// UnknownKeys returns the keys in the meta decoded from a file which Meta doesn't know.
+// They are kept in the file but never sent to Qiita.
+func (meta Meta) UnknownKeys() (keys []string) {
+	var m yaml.MapSlice
+	err := yaml.Unmarshal([]byte(meta.raw), &m)
+	if err != nil {
+		return
+	}
+	for _, item := range m {
+		key := fmt.Sprint(item.Key)
+		if !headerKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	return
 }
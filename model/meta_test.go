@@ -107,3 +107,65 @@ team:
 		}
 	}
 }
+
+func TestMetaEncodeWithSlideAndOrganization(t *testing.T) {
+	at := model.Time{Time: time.Date(2011, 2, 3, 4, 5, 6, 0, time.UTC)}
+	organization := "increments"
+	meta := model.Meta{
+		ID:                  "4bd431809afb1bb99e4f",
+		URL:                 "https://qiita.com/yaotti/items/4bd431809afb1bb99e4f",
+		CreatedAt:           at,
+		UpdatedAt:           at,
+		Slide:               true,
+		OrganizationURLName: &organization,
+		Tags:                model.Tags{model.Tag{Name: "Go"}},
+	}
+	expected := `id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2011-02-03T13:05:06+09:00
+updated_at: 2011-02-03T13:05:06+09:00
+private: false
+coediting: false
+slide: true
+organization_url_name: increments
+tags:
+- Go
+team: null`
+	actual := meta.Encode()
+	if actual != expected {
+		t.Errorf("wrong string:\n%s", testutil.Diff(expected, actual))
+	}
+
+	var decoded model.Meta
+	err := decoded.Decode(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Slide {
+		t.Errorf("wrong Slide")
+	}
+	if decoded.OrganizationURLName == nil || *decoded.OrganizationURLName != "increments" {
+		t.Errorf("wrong OrganizationURLName: %v", decoded.OrganizationURLName)
+	}
+}
+
+func TestMetaDecodeWithUnknownKey(t *testing.T) {
+	var meta model.Meta
+	err := meta.Decode(`id: 4bd431809afb1bb99e4f
+private: true
+reviewers:
+- yaotti
+tags:
+- Go
+team: null`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ID != "4bd431809afb1bb99e4f" || !meta.Private {
+		t.Errorf("known keys should be decoded: %+v", meta)
+	}
+	keys := meta.UnknownKeys()
+	if len(keys) != 1 || keys[0] != "reviewers" {
+		t.Errorf("wrong unknown keys: %v", keys)
+	}
+}
@@ -25,6 +25,11 @@ const (
 
 # {{.Title}}
 
+{{.Body}}`
+	frontMatterTemplate = `---
+{{.EncodeFrontMatter}}
+---
+
 {{.Body}}`
 
 	// DirMine is the directory of saving posts in Qiita. (Not for posts in Qiita:Team)
@@ -32,14 +37,21 @@ const (
 )
 
 var (
-	rPostDecoder = regexp.MustCompile(`^(?ms:\n*<!--(.*?)-->\n{2,}# +(.*?)\n+(.*))$`)
-	tmpl         = func() (t *template.Template) {
+	rPostDecoder        = regexp.MustCompile(`^(?ms:\n*<!--(.*?)-->\n{2,}# +(.*?)\n+(.*))$`)
+	rFrontMatterDecoder = regexp.MustCompile(`^(?s:\n*---\n(.*?)\n---(?:\n+(.*))?)$`)
+	tmpl                = func() (t *template.Template) {
 		t = template.New("postfile")
 		template.Must(t.Parse(postTemplate))
 		return
 	}()
+	frontMatterTmpl = func() (t *template.Template) {
+		t = template.New("frontmatterfile")
+		template.Must(t.Parse(frontMatterTemplate))
+		return
+	}()
 	rInvalidBasename = regexp.MustCompile(`[\\\/?:*"<>|]+`)
 	rHyphens         = regexp.MustCompile(`\-{2,}`)
+	rNonSlug         = regexp.MustCompile(`[^\pL\pN]+`)
 )
 
 // Post is a post in Qiita.
@@ -50,6 +62,7 @@ type Post struct {
 	Body         string `json:"body"`          // Markdown形式の本文
 	RenderedBody string `json:"rendered_body"` // HTML形式の本文
 	Path         string `json:"-"`
+	Format       Format `json:"-"`
 }
 
 // CreationOptions is options for creating a post.
@@ -152,6 +165,8 @@ func (post *Post) Create(client api.Client, opts CreationOptions) (err error) {
 	if err != nil {
 		return
 	}
+	post.Draft = false
+	post.PublishAt = nil
 	return
 }
 
@@ -175,6 +190,12 @@ func FetchPost(client api.Client, team *Team, id string) (post Post, err error)
 
 // Update updates a post in Qiita.
 func (post *Post) Update(client api.Client) (err error) {
+	if post.Draft {
+		err = DraftError{
+			Path: post.Path,
+		}
+		return
+	}
 	if post.ID == "" {
 		err = EmptyIDError{}
 		return
@@ -197,6 +218,12 @@ func (post *Post) Update(client api.Client) (err error) {
 
 // Delete deletes a post in Qiita.
 func (post *Post) Delete(client api.Client) (err error) {
+	if post.Draft {
+		err = DraftError{
+			Path: post.Path,
+		}
+		return
+	}
 	if post.ID == "" {
 		err = EmptyIDError{}
 		return
@@ -213,10 +240,36 @@ func (post *Post) Delete(client api.Client) (err error) {
 // Save saves a post as a markdown file in local.
 func (post *Post) Save(cachedPaths map[string]string) (err error) {
 	if cachedPaths == nil {
-		cachedPaths = pathsInLocal()
+		cachedPaths, err = pathsInLocal()
+		if err != nil {
+			return
+		}
 	}
 
-	post.fillPath(cachedPaths)
+	err = post.fillPath(cachedPaths)
+	if err != nil {
+		return
+	}
+
+	if post.Meta.raw == "" {
+		// Keep the unknown keys and comments in the existing file.
+		raw, format, ok := readHeader(post.Path)
+		if ok {
+			post.Meta.raw = raw
+			if post.Format == "" {
+				post.Format = format
+			}
+		}
+	}
+
+	if post.Format == "" {
+		var config Config
+		config, err = LoadConfig()
+		if err != nil {
+			return
+		}
+		post.Format = config.Format
+	}
 
 	dir := filepath.Dir(post.Path)
 	err = os.MkdirAll(dir, 0755)
@@ -238,63 +291,123 @@ func (post *Post) Save(cachedPaths map[string]string) (err error) {
 	return
 }
 
-func (post *Post) fillPath(paths map[string]string) {
-	for id, path := range paths {
-		if id == post.ID {
-			post.Path = path
+// SaveMerged saves a post fetched from Qiita as a markdown file in local.
+// When the snapshot saved at the last fetch exists, the local changes since then are merged.
+// When the file exists without the snapshot, like the files fetched by the older versions,
+// the lines which differ from the remote post conflict.
+// false is returned when any conflict is left in the file.
+// The code blocks expanded from include directives are collapsed into the directives,
+// and the URLs of the posts in cachedPaths are rewritten into the relative paths to their files.
+// The post is saved as the new snapshot.
+func (post *Post) SaveMerged(cachedPaths map[string]string) (clean bool, err error) {
+	clean = true
+	if cachedPaths == nil {
+		cachedPaths, err = pathsInLocal()
+		if err != nil {
 			return
 		}
 	}
+	remote := *post
+	err = post.fillPath(cachedPaths)
+	if err != nil {
+		return
+	}
 
-	if post.Path != "" {
+	base, err := LoadBase(post.ID)
+	if err != nil {
 		return
 	}
+	local, e := NewPostWithFile(post.Path)
+	if e == nil {
+		if base == nil {
+			// Compare the remote post with the local file as it would be saved.
+			normalized := remote
+			normalized.Path = post.Path
+			normalized.Body = CollapseIncludes(normalized.Body)
+			normalized.RelativizeLinks(cachedPaths)
+			*post = local
+			clean = post.Merge(nil, normalized)
+		} else {
+			*post = local
+			clean = post.Merge(base, remote)
+		}
+	}
+	post.Body = CollapseIncludes(post.Body)
+	post.RelativizeLinks(cachedPaths)
 
-	post.Path = post.createPath()
+	err = post.Save(cachedPaths)
+	if err != nil {
+		return
+	}
+	err = remote.SaveBase()
+	if err != nil {
+		return
+	}
+	if !clean {
+		err = AddConflict(post.Path)
+	}
 	return
 }
 
-func pathsInLocal() (paths map[string]string) {
-	paths = make(map[string]string)
-	filepath.Walk(".", func(p string, i os.FileInfo, e error) (err error) {
-		if e != nil {
-			err = e
-			return
-		}
-		if i.IsDir() {
-			return
-		}
-		if filepath.Ext(p) != ".md" {
-			return
-		}
+// readHeader reads the header of the file at path without decoding it.
+func readHeader(path string) (raw string, format Format, ok bool) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if matched := rFrontMatterDecoder.FindSubmatch(b); matched != nil {
+		raw = string(bytes.TrimSpace(matched[1]))
+		format = FormatFrontMatter
+		ok = true
+		return
+	}
+	if matched := rPostDecoder.FindSubmatch(b); matched != nil {
+		raw = string(bytes.TrimSpace(matched[1]))
+		format = FormatComment
+		ok = true
+	}
+	return
+}
 
-		post, err := NewPostWithFile(p)
-		if err != nil {
-			return nil
-		}
-		if post.ID == "" {
+func (post *Post) fillPath(paths map[string]string) (err error) {
+	for id, path := range paths {
+		if id == post.ID {
+			post.Path = path
 			return
 		}
-		paths[post.ID] = p
+	}
+
+	if post.Path != "" {
 		return
-	})
+	}
+
+	post.Path, err = post.createPath()
 	return
 }
 
-func (post Post) createPath() (path string) {
-	var dirname string
-	if post.Team == nil {
-		dirname = DirMine
-	} else {
-		dirname = post.Team.ID
+func pathsInLocal() (paths map[string]string, err error) {
+	index, err := UpdateIndex()
+	if err != nil {
+		return
 	}
-	dirname = filepath.Join(dirname, post.CreatedAt.Format("2006/01/02"))
+	paths = index.Paths()
+	return
+}
 
-	basename := rInvalidBasename.ReplaceAllString(post.Title, "-")
-	basename = rHyphens.ReplaceAllString(basename, "-")
+func (post Post) createPath() (path string, err error) {
+	path, err = post.layoutPath()
+	if err != nil {
+		return
+	}
+	path = uniquePath(path)
+	return
+}
 
+// uniquePath appends hyphens to the basename of path until no file exists at the path.
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	basename := strings.TrimSuffix(path, ext)
 	for {
-		path = filepath.Join(dirname, fmt.Sprintf("%s.md", basename))
 		_, err := os.Stat(path)
 		// without error: file exists at the path
 		// with error: file doesn't exist at the path
@@ -304,30 +417,256 @@ func (post Post) createPath() (path string) {
 			break
 		}
 		basename += "-"
+		path = basename + ext
+	}
+	return path
+}
+
+// layoutPath makes the path of the file with the path template of the workspace.
+func (post Post) layoutPath() (path string, err error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return
+	}
+	if config.PathTemplate == "" {
+		dirname := filepath.Join(post.TeamID(), post.CreatedAt.Format("2006/01/02"))
+		basename := rInvalidBasename.ReplaceAllString(post.Title, "-")
+		basename = rHyphens.ReplaceAllString(basename, "-")
+		path = filepath.Join(dirname, fmt.Sprintf("%s.md", basename))
+		return
+	}
+
+	t, err := template.New("path").Parse(config.PathTemplate)
+	if err != nil {
+		return
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = t.Execute(buf, post)
+	if err != nil {
+		return
+	}
+	path = filepath.Clean(buf.String())
+	return
+}
+
+// TeamID returns the ID of the team of the post, or DirMine for the post in Qiita.
+func (post Post) TeamID() (id string) {
+	if post.Team == nil {
+		id = DirMine
+		return
+	}
+	id = post.Team.ID
+	return
+}
+
+// Slug returns the title in lower case whose symbols and spaces are replaced with hyphens.
+func (post Post) Slug() (slug string) {
+	slug = rNonSlug.ReplaceAllString(strings.ToLower(post.Title), "-")
+	slug = strings.Trim(slug, "-")
+	return
+}
+
+// Rename moves the file of the post to the path made with the path template of the workspace,
+// and returns the new path.
+// The mirrored images linked from the post, its record of unresolved conflicts
+// and its preview are moved with the file.
+// The file isn't moved when dryRun is true or the file is already at the path.
+func (post *Post) Rename(dryRun bool) (path string, err error) {
+	path, err = post.layoutPath()
+	if err != nil {
+		return
+	}
+	if path == filepath.Clean(post.Path) {
+		return
+	}
+	path, err = post.createPath()
+	if err != nil {
+		return
+	}
+	if dryRun {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return
+	}
+	err = post.copyAssets(filepath.Dir(path))
+	if err != nil {
+		return
+	}
+	from := post.Path
+	err = os.Rename(from, path)
+	if err != nil {
+		return
+	}
+	post.Path = path
+	err = RenameConflict(from, path)
+	if err != nil {
+		return
+	}
+	err = RenamePreview(from, path)
+	if err != nil {
+		return
+	}
+	err = removeAssets(filepath.Dir(from), post.Assets)
+	return
+}
+
+// copyAssets copies the mirrored images linked from the post into dir,
+// where they are linked with the same relative links.
+func (post Post) copyAssets(dir string) (err error) {
+	for link := range post.Assets {
+		src := filepath.Join(filepath.Dir(post.Path), filepath.FromSlash(link))
+		dst := filepath.Join(dir, filepath.FromSlash(link))
+		if src == dst {
+			continue
+		}
+		var b []byte
+		b, err = ioutil.ReadFile(src)
+		if err != nil {
+			if os.IsNotExist(err) {
+				err = nil
+				continue
+			}
+			return
+		}
+		err = os.MkdirAll(filepath.Dir(dst), 0755)
+		if err != nil {
+			return
+		}
+		err = ioutil.WriteFile(dst, b, 0644)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// removeAssets removes the mirrored images of links from dir
+// unless they are linked from the other posts in dir,
+// and removes the directory of the images when it gets empty.
+func removeAssets(dir string, links map[string]string) (err error) {
+	if len(links) == 0 {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return
+	}
+	used := make(map[string]bool)
+	for _, file := range files {
+		post, e := NewPostWithFile(file)
+		if e != nil {
+			continue
+		}
+		for link := range post.Assets {
+			used[link] = true
+		}
+	}
+	for link := range links {
+		if used[link] {
+			continue
+		}
+		p := filepath.Join(dir, filepath.FromSlash(link))
+		err = os.Remove(p)
+		if err != nil && !os.IsNotExist(err) {
+			return
+		}
+		err = nil
+		os.Remove(filepath.Dir(p))
 	}
 	return
 }
 
 // Encode encodes Post from bytes.
 func (post Post) Encode(w io.Writer) (err error) {
-	err = tmpl.Execute(w, post)
+	switch post.Format {
+	case FormatFrontMatter:
+		err = frontMatterTmpl.Execute(w, post)
+	default:
+		err = tmpl.Execute(w, post)
+	}
+	return
+}
+
+// EncodeFrontMatter marshals the title and meta as YAML front matter.
+// The error is returned through Encode, which executes the template with it.
+func (post Post) EncodeFrontMatter() (out string, err error) {
+	type frontMatter struct {
+		Title string `yaml:"title"`
+		Meta  `yaml:",inline"`
+	}
+	o, err := yaml.Marshal(frontMatter{
+		Title: post.Title,
+		Meta:  post.Meta,
+	})
+	if err != nil {
+		return
+	}
+	out = string(bytes.TrimSpace(o))
+	if post.Meta.raw != "" {
+		out = mergeHeader(post.Meta.raw, out, headerKeys)
+	}
 	return
 }
 
 // Decode decodes Post from bytes.
+// The format is detected from the content.
 func (post *Post) Decode(b []byte) (err error) {
+	if rFrontMatterDecoder.Match(b) {
+		err = post.decodeFrontMatter(b)
+		return
+	}
+
 	matched := rPostDecoder.FindSubmatch(b)
 	if len(matched) != 4 {
 		err = fmt.Errorf("wrong format")
 		return
 	}
 
-	err = yaml.Unmarshal((bytes.TrimSpace(matched[1])), &post.Meta)
+	err = post.Meta.Decode(string(bytes.TrimSpace(matched[1])))
 	if err != nil {
 		return
 	}
 	post.Title = string(bytes.TrimSpace(matched[2]))
 	post.Body = string(bytes.TrimSpace(matched[3]))
+	post.Format = FormatComment
+	return
+}
+
+func (post *Post) decodeFrontMatter(b []byte) (err error) {
+	matched := rFrontMatterDecoder.FindSubmatch(b)
+
+	var m yaml.MapSlice
+	err = yaml.Unmarshal(matched[1], &m)
+	if err != nil {
+		return
+	}
+	var meta yaml.MapSlice
+	for _, item := range m {
+		if item.Key == "title" {
+			post.Title = strings.TrimSpace(fmt.Sprint(item.Value))
+			continue
+		}
+		meta = append(meta, item)
+	}
+	if post.Title == "" {
+		err = fmt.Errorf("wrong format: title is missing in front matter")
+		return
+	}
+
+	o, err := yaml.Marshal(meta)
+	if err != nil {
+		return
+	}
+	err = post.Meta.Decode(string(o))
+	if err != nil {
+		return
+	}
+	post.Meta.raw = string(bytes.TrimSpace(matched[1]))
+	post.Body = string(bytes.TrimSpace(matched[2]))
+	post.Format = FormatFrontMatter
 	return
 }
 
@@ -339,6 +678,16 @@ func (err EmptyIDError) Error() (msg string) {
 	return
 }
 
+// DraftError occurs when operate a draft which isn't published yet.
+type DraftError struct {
+	Path string
+}
+
+func (err DraftError) Error() (msg string) {
+	msg = fmt.Sprintf("%s is a draft: publish it with `qiitactl publish` first", err.Path)
+	return
+}
+
 // InvalidError occurs when some fields are wrong.
 type InvalidError map[string]InvalidStatus
 
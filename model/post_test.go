@@ -833,6 +833,10 @@ func TestPostUpdate(t *testing.T) {
 			return
 		}
 
+		if strings.Contains(string(b), "organization_url_name") {
+			testutil.ResponseError(w, 500, errors.New("organization_url_name shouldn't be sent to a team"))
+			return
+		}
 		var post model.Post
 		err = json.Unmarshal(b, &post)
 		if err != nil {
@@ -897,6 +901,91 @@ func TestPostUpdate(t *testing.T) {
 	testutil.ShouldExistFile(t, 0)
 }
 
+func TestPostUpdateWithSlideAndOrganization(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/items/abcdefghijklmnopqrst", func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			testutil.ResponseError(w, 500, err)
+			return
+		}
+
+		var sent map[string]interface{}
+		err = json.Unmarshal(b, &sent)
+		if err != nil {
+			testutil.ResponseError(w, 500, err)
+			return
+		}
+		if sent["slide"] != true {
+			testutil.ResponseError(w, 500, errors.New("slide should be sent"))
+			return
+		}
+		if sent["organization_url_name"] != "increments" {
+			testutil.ResponseError(w, 500, errors.New("organization_url_name should be sent"))
+			return
+		}
+		if _, ok := sent["reviewers"]; ok {
+			testutil.ResponseError(w, 500, errors.New("unknown keys shouldn't be sent"))
+			return
+		}
+
+		w.Write(b)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := api.NewClient(func(subDomain, path string) (url string) {
+		url = fmt.Sprintf("%s%s%s", server.URL, "/api/v2", path)
+		return
+	}, inf)
+
+	var post model.Post
+	err = post.Decode([]byte(`<!--
+id: abcdefghijklmnopqrst
+url: http://example.com/mypost
+created_at: 2013-12-10T12:29:14+09:00
+updated_at: 2015-02-25T09:26:30+09:00
+private: false
+coediting: false
+slide: true
+organization_url_name: increments
+reviewers:
+- yaotti
+tags:
+- Go
+team: null
+-->
+
+# Main title
+
+Paragraph`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = post.Update(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !post.Slide {
+		t.Errorf("wrong Slide")
+	}
+	if post.OrganizationURLName == nil || *post.OrganizationURLName != "increments" {
+		t.Errorf("wrong OrganizationURLName: %v", post.OrganizationURLName)
+	}
+}
+
 func TestPostUpdateInTeam(t *testing.T) {
 	testutil.CleanUp()
 	defer testutil.CleanUp()
@@ -1015,6 +1104,29 @@ func TestPostUpdateWithEmptyID(t *testing.T) {
 	testutil.ShouldExistFile(t, 0)
 }
 
+func TestPostUpdateWithDraft(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.Setenv("QIITA_ACCESS_TOKEN", "XXXXXXXXXXXX")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := api.NewClient(nil, inf)
+
+	post := model.NewPost("Example Title", nil, nil)
+	post.Draft = true
+	post.Path = "mine/2000/01/01/Example Title.md"
+	err = post.Update(client)
+	err, ok := err.(model.DraftError)
+	if !ok {
+		t.Fatal("draft error should occur")
+	}
+	if err.Error() != "mine/2000/01/01/Example Title.md is a draft: publish it with `qiitactl publish` first" {
+		t.Errorf("wrong error: %s", err)
+	}
+}
+
 func TestPostUpdateWithNoServer(t *testing.T) {
 	testutil.CleanUp()
 	defer testutil.CleanUp()
@@ -1619,6 +1731,46 @@ team: null
 	}()
 }
 
+func TestPostSaveWithPathTemplate(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll(".qiitactl", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(model.ConfigPath, []byte("path_template: '{{.TeamID}}/{{index .Tags 0}}/{{.Slug}}.md'\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := model.NewPost("Hello, World: Go 1.6!", nil, &model.Team{ID: "increments"})
+	post.Tags = model.Tags{model.Tag{Name: "Go", Versions: []string{"1.6"}}}
+	err = post.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Path != "increments/Go/hello-world-go-1-6.md" {
+		t.Errorf("wrong path: %s", post.Path)
+	}
+
+	post = model.NewPost("Hello, World: Go 1.6!", nil, &model.Team{ID: "increments"})
+	post.Tags = model.Tags{model.Tag{Name: "Go"}}
+	err = post.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Path != "increments/Go/hello-world-go-1-6-.md" {
+		t.Errorf("wrong path: %s", post.Path)
+	}
+
+	post = model.NewPost("No tags", nil, nil)
+	err = post.Save(nil)
+	if err == nil {
+		t.Errorf("error should occur when the template fails")
+	}
+}
+
 func TestPostSaveDuplicationWithID(t *testing.T) {
 	testutil.CleanUp()
 	defer testutil.CleanUp()
@@ -1700,6 +1852,195 @@ team: null
 	}
 }
 
+func TestPostEncodeWithFrontMatter(t *testing.T) {
+	post := model.NewPost("Example: title", &model.Time{time.Date(2016, 2, 2, 6, 30, 46, 0, time.UTC)}, nil)
+	post.ID = "4bd431809afb1bb99e4f"
+	post.URL = "https://qiita.com/yaotti/items/4bd431809afb1bb99e4f"
+	post.Body = "## Sub title\nParagraph"
+	post.Format = model.FormatFrontMatter
+	buf := bytes.NewBuffer([]byte{})
+	err := post.Encode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := string(buf.Bytes())
+	expected := `---
+title: 'Example: title'
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2016-02-02T15:30:46+09:00
+updated_at: 2016-02-02T15:30:46+09:00
+private: false
+coediting: false
+tags: []
+team: null
+---
+
+## Sub title
+Paragraph`
+	if expected != actual {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+
+	var decoded model.Post
+	err = decoded.Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Format != model.FormatFrontMatter {
+		t.Errorf("wrong Format: %s", decoded.Format)
+	}
+	if decoded.Title != post.Title {
+		t.Errorf("wrong Title: %s", decoded.Title)
+	}
+	if decoded.ID != post.ID {
+		t.Errorf("wrong ID: %s", decoded.ID)
+	}
+	if !decoded.CreatedAt.Equal(post.CreatedAt.Time) {
+		t.Errorf("wrong CreatedAt: %s", decoded.CreatedAt)
+	}
+	if decoded.Body != post.Body {
+		t.Errorf("wrong Body: %s", decoded.Body)
+	}
+}
+
+func TestPostDecodeWithFrontMatterWithoutTitle(t *testing.T) {
+	var post model.Post
+	err := post.Decode([]byte(`---
+id: abcdefghijklmnopqrst
+tags:
+- Go
+---
+
+Paragraph`))
+	if err == nil {
+		t.Errorf("front matter without title should return error")
+	}
+}
+
+func TestPostSaveWithFrontMatterWorkspace(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	err := os.MkdirAll(".qiitactl", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(model.ConfigPath, []byte("format: front_matter\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := model.NewPost("Example Title", &model.Time{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}, nil)
+	err = post.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile("mine/2000/01/01/Example Title.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "---\ntitle: Example Title\n") {
+		t.Errorf("file should be saved with front matter:\n%s", b)
+	}
+}
+
+func TestPostEncodeWithUnknownKeysAndComments(t *testing.T) {
+	var post model.Post
+	err := post.Decode([]byte(`<!--
+# Reviewed by the team before publishing
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2000-01-01T09:00:00+09:00
+series: tutorial # part 1
+private: false
+coediting: false
+tags:
+# keep Go first
+- Go
+reviewers:
+- yaotti
+- minodisk
+team: null
+-->
+
+# Example Title
+
+Paragraph`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post.UpdatedAt = model.Time{Time: time.Date(2016, 2, 1, 12, 51, 42, 0, time.UTC)}
+	post.Private = true
+	post.Slide = true
+
+	buf := bytes.NewBuffer([]byte{})
+	err = post.Encode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := buf.String()
+	expected := `<!--
+# Reviewed by the team before publishing
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2016-02-01T21:51:42+09:00
+series: tutorial # part 1
+private: true
+coediting: false
+slide: true
+tags:
+# keep Go first
+- Go
+reviewers:
+- yaotti
+- minodisk
+team: null
+-->
+
+# Example Title
+
+Paragraph`
+	if actual != expected {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+
+	post.Slide = false
+	post.Format = model.FormatFrontMatter
+	buf = bytes.NewBuffer([]byte{})
+	err = post.Encode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = buf.String()
+	expected = `---
+title: Example Title
+# Reviewed by the team before publishing
+id: 4bd431809afb1bb99e4f
+url: https://qiita.com/yaotti/items/4bd431809afb1bb99e4f
+created_at: 2000-01-01T09:00:00+09:00
+updated_at: 2016-02-01T21:51:42+09:00
+series: tutorial # part 1
+private: true
+coediting: false
+tags:
+# keep Go first
+- Go
+reviewers:
+- yaotti
+- minodisk
+team: null
+---
+
+Paragraph`
+	if actual != expected {
+		t.Errorf("wrong content:\n%s", testutil.Diff(expected, actual))
+	}
+}
+
 func TestPostDecodeWithWrongMeta(t *testing.T) {
 	testutil.CleanUp()
 	defer testutil.CleanUp()
@@ -3,7 +3,10 @@ package model
 import (
 	"encoding/json"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/minodisk/qiitactl/api"
 )
@@ -56,12 +59,143 @@ func FetchPosts(client api.Client, team *Team) (posts Posts, err error) {
 
 // Save saves posts into current working directory as markdown files.
 func (posts Posts) Save() (err error) {
-	paths := pathsInLocal()
+	paths, err := pathsInLocal()
+	if err != nil {
+		return
+	}
+	for i := range posts {
+		err = posts[i].Save(paths)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// FetchModifiedPosts finds the local files of posts modified since they were fetched or updated last time.
+// The contents are compared with the snapshots by hash, so the files without snapshots aren't found.
+// The drafts aren't found even if they have IDs.
+func FetchModifiedPosts() (posts Posts, err error) {
+	err = WalkPosts(".", func(post Post) (err error) {
+		if post.ID == "" || post.Draft {
+			return
+		}
+		base, err := LoadBase(post.ID)
+		if err != nil || base == nil {
+			return
+		}
+		if post.ContentHash() != base.ContentHash() {
+			posts = append(posts, post)
+		}
+		return
+	})
+	return
+}
+
+// SaveMerged saves posts fetched from Qiita with the local changes merged,
+// and returns the paths of the files where conflicts are left.
+func (posts Posts) SaveMerged() (conflicts []string, err error) {
+	paths, err := pathsInLocal()
+	if err != nil {
+		return
+	}
+	for i := range posts {
+		var clean bool
+		clean, err = posts[i].SaveMerged(paths)
+		if err != nil {
+			return
+		}
+		if !clean {
+			conflicts = append(conflicts, posts[i].Path)
+		}
+	}
+
+	// The links to the posts saved above are rewritten now that their files exist.
 	for _, post := range posts {
-		err = post.Save(paths)
+		paths[post.ID] = post.Path
+	}
+	for i := range posts {
+		if !posts[i].RelativizeLinks(paths) {
+			continue
+		}
+		err = posts[i].Save(paths)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// WalkPosts calls fn with every post in the files under root.
+// Files which aren't posts and the ignored directories are skipped.
+func WalkPosts(root string, fn func(post Post) error) (err error) {
+	err = filepath.Walk(root, func(p string, i os.FileInfo, e error) (err error) {
+		if e != nil {
+			err = e
+			return
+		}
+		if i.IsDir() {
+			if p != root && isIgnoredDir(i.Name()) {
+				err = filepath.SkipDir
+			}
+			return
+		}
+		if filepath.Ext(p) != ".md" {
+			return
+		}
+
+		post, err := NewPostWithFile(p)
 		if err != nil {
+			return nil
+		}
+		err = fn(post)
+		return
+	})
+	return
+}
+
+// FetchLocalPosts loads the local files of posts in current working directory.
+func FetchLocalPosts() (posts Posts, err error) {
+	err = WalkPosts(".", func(post Post) (err error) {
+		posts = append(posts, post)
+		return
+	})
+	return
+}
+
+// FetchDrafts loads the local files of posts which aren't published yet.
+func FetchDrafts() (posts Posts, err error) {
+	err = WalkPosts(".", func(post Post) (err error) {
+		if post.ID != "" {
 			return
 		}
+		posts = append(posts, post)
+		return
+	})
+	return
+}
+
+// Due returns the posts which are scheduled to be published by now.
+func (posts Posts) Due(now time.Time) (due Posts) {
+	for _, post := range posts {
+		if post.PublishAt == nil || post.PublishAt.After(now) {
+			continue
+		}
+		due = append(due, post)
+	}
+	return
+}
+
+// NextPublishAt returns the earliest time when one of the posts is scheduled to be published after now.
+// It returns nil when no post is scheduled.
+func (posts Posts) NextPublishAt(now time.Time) (next *Time) {
+	for _, post := range posts {
+		if post.PublishAt == nil || !post.PublishAt.After(now) {
+			continue
+		}
+		if next == nil || post.PublishAt.Before(next.Time) {
+			next = post.PublishAt
+		}
 	}
 	return
 }
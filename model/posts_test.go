@@ -478,3 +478,32 @@ func BenchmarkPostsSave(b *testing.B) {
 		b.Fatal(err)
 	}
 }
+
+func TestPostsDue(t *testing.T) {
+	past := model.Time{Time: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	future := model.Time{Time: time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC)}
+	later := model.Time{Time: time.Date(2000, 1, 5, 0, 0, 0, 0, time.UTC)}
+	now := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	posts := model.Posts{
+		model.Post{Title: "past", Meta: model.Meta{PublishAt: &past}},
+		model.Post{Title: "later", Meta: model.Meta{PublishAt: &later}},
+		model.Post{Title: "unscheduled"},
+		model.Post{Title: "future", Meta: model.Meta{PublishAt: &future}},
+	}
+
+	due := posts.Due(now)
+	if len(due) != 1 || due[0].Title != "past" {
+		t.Errorf("wrong due posts: %+v", due)
+	}
+
+	next := posts.NextPublishAt(now)
+	if next == nil || !next.Equal(future.Time) {
+		t.Errorf("wrong next publish_at: %v", next)
+	}
+
+	next = posts.NextPublishAt(later.Time)
+	if next != nil {
+		t.Errorf("no post should be scheduled: %v", next)
+	}
+}
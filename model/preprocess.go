@@ -0,0 +1,311 @@
+package model
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/minodisk/qiitactl/asset"
+	"gopkg.in/yaml.v2"
+)
+
+// VariablesPath is the path of the file which defines the variables used in the bodies of posts.
+var VariablesPath = filepath.Join(DirWorkspace, "variables.yml")
+
+// includeEndMarker closes the code block expanded from an include directive.
+const includeEndMarker = "<!-- /include -->"
+
+var (
+	rCodeFence   = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+	rCodeSpan    = regexp.MustCompile("`+[^`]*`+")
+	rVariable    = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_\-]*)\s*\}\}`)
+	rInclude     = regexp.MustCompile(`^<!--\s*include:\s*(\S+)((?:\s+[a-z]+=\S+)*)\s*-->\s*$`)
+	rBackticks   = regexp.MustCompile("`{3,}")
+	includeLangs = map[string]string{
+		".c":    "c",
+		".cpp":  "cpp",
+		".css":  "css",
+		".go":   "go",
+		".html": "html",
+		".java": "java",
+		".js":   "javascript",
+		".json": "json",
+		".py":   "python",
+		".rb":   "ruby",
+		".rs":   "rust",
+		".sh":   "bash",
+		".sql":  "sql",
+		".ts":   "typescript",
+		".yaml": "yaml",
+		".yml":  "yaml",
+	}
+)
+
+// nextFence returns the fence of the code block after line, when line opens or closes a code block.
+// fence is the fence of the code block which line is in, or empty outside of code blocks.
+func nextFence(fence, line string) (next string, ok bool) {
+	next = fence
+	m := rCodeFence.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	if fence == "" {
+		next, ok = m[1], true
+		return
+	}
+	if m[1][0] == fence[0] && len(m[1]) >= len(fence) && strings.TrimSpace(m[2]) == "" {
+		next, ok = "", true
+	}
+	return
+}
+
+// mapOutsideCode replaces every line in body outside of code blocks with the one returned by fn.
+func mapOutsideCode(body string, fn func(line string) string) string {
+	lines := strings.Split(body, "\n")
+	fence := ""
+	for i, line := range lines {
+		next, ok := nextFence(fence, line)
+		if ok || fence != "" {
+			fence = next
+			continue
+		}
+		lines[i] = fn(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mapOutsideCodeSpans replaces every text in line between code spans with the one returned by fn.
+func mapOutsideCodeSpans(line string, fn func(text string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range append(rCodeSpan.FindAllStringIndex(line, -1), []int{len(line), len(line)}) {
+		b.WriteString(fn(line[last:loc[0]]))
+		b.WriteString(line[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	return b.String()
+}
+
+// Variables are the values which replace the placeholders like {{name}} in the bodies of posts.
+type Variables map[string]string
+
+// LoadVariables loads the variables from VariablesPath.
+// No variables are returned when the file doesn't exist.
+func LoadVariables() (vars Variables, err error) {
+	vars = make(Variables)
+	b, err := ioutil.ReadFile(VariablesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	err = yaml.Unmarshal(b, &vars)
+	return
+}
+
+// Expand returns the body whose placeholders of variables are replaced with their values
+// and whose include directives are expanded into code blocks.
+// The placeholders and the directives in code are kept as they are.
+// A block already expanded from a directive is replaced with the new one,
+// or kept when the file can't be included.
+// ExpandError is returned with the problems while the others are expanded in the body.
+func (post Post) Expand() (body string, err error) {
+	body = post.Body
+	vars, err := LoadVariables()
+	if err != nil {
+		return
+	}
+	var problems []string
+	body = mapOutsideCode(post.Body, func(line string) string {
+		return mapOutsideCodeSpans(line, func(text string) string {
+			return rVariable.ReplaceAllStringFunc(text, func(s string) string {
+				name := rVariable.FindStringSubmatch(s)[1]
+				value, ok := vars[name]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: undefined variable", s))
+					return s
+				}
+				return value
+			})
+		})
+	})
+
+	dir := filepath.Dir(post.Path)
+	src := strings.Split(body, "\n")
+	var lines []string
+	fence := ""
+	for i := 0; i < len(src); i++ {
+		line := src[i]
+		next, ok := nextFence(fence, line)
+		if ok || fence != "" {
+			fence = next
+			lines = append(lines, line)
+			continue
+		}
+		m := rInclude.FindStringSubmatch(line)
+		if m == nil {
+			lines = append(lines, line)
+			continue
+		}
+		lines = append(lines, line)
+		end := includeEnd(src, i+1)
+		block, e := include(dir, m[1], m[2])
+		switch {
+		case e == nil:
+			lines = append(lines, block, includeEndMarker)
+		case end > 0:
+			lines = append(lines, src[i+1:end+1]...)
+		}
+		if e != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", strings.TrimSpace(line), e))
+		}
+		if end > 0 {
+			i = end
+		}
+	}
+	body = strings.Join(lines, "\n")
+
+	if len(problems) > 0 {
+		err = ExpandError{
+			Path:     post.Path,
+			Problems: problems,
+		}
+	}
+	return
+}
+
+// include returns the code block with the lines of the file at path relative to dir.
+// options are the options of the directive such as `lines=10-30` and `lang=go`.
+func include(dir, path, options string) (block string, err error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+	if err != nil {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	lang, ok := includeLangs[filepath.Ext(path)]
+	if !ok {
+		lang = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	for _, option := range strings.Fields(options) {
+		kv := strings.SplitN(option, "=", 2)
+		switch kv[0] {
+		case "lines":
+			lines, err = selectLines(lines, kv[1])
+			if err != nil {
+				return
+			}
+		case "lang":
+			lang = kv[1]
+		default:
+			err = fmt.Errorf("unknown option %s", kv[0])
+			return
+		}
+	}
+
+	code := strings.Join(lines, "\n")
+	fence := codeFence(code)
+	block = fmt.Sprintf("%s%s:%s\n%s\n%s", fence, lang, filepath.Base(path), code, fence)
+	return
+}
+
+// selectLines returns the lines in the range like `10-30`, `10-` or `10` counted from 1.
+func selectLines(lines []string, r string) (selected []string, err error) {
+	bounds := strings.SplitN(r, "-", 2)
+	start, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return
+	}
+	end := start
+	if len(bounds) == 2 {
+		end = len(lines)
+		if bounds[1] != "" {
+			end, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return
+			}
+		}
+	}
+	if start < 1 || end < start || end > len(lines) {
+		err = fmt.Errorf("lines %s are out of the file with %d lines", r, len(lines))
+		return
+	}
+	selected = lines[start-1 : end]
+	return
+}
+
+// CollapseIncludes replaces the code blocks expanded from include directives in body with the directives,
+// so that the file fetched from Qiita keeps the directives.
+func CollapseIncludes(body string) string {
+	lines := strings.Split(body, "\n")
+	var collapsed []string
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if fence == "" && rInclude.MatchString(line) {
+			if end := includeEnd(lines, i+1); end > 0 {
+				collapsed = append(collapsed, line)
+				i = end
+				continue
+			}
+		}
+		fence, _ = nextFence(fence, line)
+		collapsed = append(collapsed, line)
+	}
+	return strings.Join(collapsed, "\n")
+}
+
+// includeEnd returns the index of the end marker of the code block starting at start
+// which is expanded from a directive, or -1 when the lines aren't expanded from a directive.
+func includeEnd(lines []string, start int) int {
+	if start >= len(lines) {
+		return -1
+	}
+	fence, ok := nextFence("", lines[start])
+	if !ok {
+		return -1
+	}
+	for i := start + 1; i+1 < len(lines); i++ {
+		if next, ok := nextFence(fence, lines[i]); ok && next == "" {
+			if lines[i+1] == includeEndMarker {
+				return i + 1
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// SentBody returns the body to be sent to Qiita.
+// The links to mirrored images are restored to the original URLs,
+// the relative links to other posts are resolved into their URLs,
+// and the variables and the include directives are expanded.
+// The error of any step is returned with the body processed as far as possible.
+func (post Post) SentBody() (body string, err error) {
+	post.Body = asset.RestoreLinks(post.Body, post.Assets)
+	post.Body, err = post.ResolveLinks()
+	body, e := post.Expand()
+	if e != nil && err == nil {
+		err = e
+	}
+	return
+}
+
+// ExpandError occurs when the variables or the include directives in a post can't be expanded.
+type ExpandError struct {
+	Path     string
+	Problems []string
+}
+
+func (err ExpandError) Error() (msg string) {
+	msgs := []string{fmt.Sprintf("%s can't be expanded:", err.Path)}
+	for _, problem := range err.Problems {
+		msgs = append(msgs, fmt.Sprintf("- %s", problem))
+	}
+	msg = strings.Join(msgs, "\n")
+	return
+}
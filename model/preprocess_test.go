@@ -0,0 +1,131 @@
+package model_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minodisk/qiitactl/model"
+	"github.com/minodisk/qiitactl/testutil"
+)
+
+func writePreprocessFiles(t *testing.T) {
+	files := map[string]string{
+		model.VariablesPath:  "version: 1.2.3\nname: qiitactl\n",
+		"mine/src/main.go":   "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n",
+		"mine/src/README.md": "```\ncode\n```\n",
+	}
+	for path, content := range files {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPostExpand(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+	writePreprocessFiles(t)
+
+	post := model.Post{
+		Body: `{{name}} v{{ version }} and ` + "`{{name}}`" + `
+
+<!-- include: src/main.go lines=5-7 -->
+
+<!-- include: src/README.md lang=markdown -->
+
+` + "```\n{{name}}\n<!-- include: src/main.go -->\n```",
+	}
+	post.Path = "mine/post.md"
+	body, err := post.Expand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `qiitactl v1.2.3 and ` + "`{{name}}`" + `
+
+<!-- include: src/main.go lines=5-7 -->
+` + "```go:main.go\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n```" + `
+<!-- /include -->
+
+<!-- include: src/README.md lang=markdown -->
+` + "````markdown:README.md\n```\ncode\n```\n````" + `
+<!-- /include -->
+
+` + "```\n{{name}}\n<!-- include: src/main.go -->\n```"
+	if body != expected {
+		t.Errorf("wrong body:\n%s", testutil.Diff(expected, body))
+	}
+
+	if collapsed := model.CollapseIncludes(body); collapsed != strings.Replace(post.Body, "{{name}} v{{ version }}", "qiitactl v1.2.3", 1) {
+		t.Errorf("the blocks should be collapsed into the directives:\n%s", collapsed)
+	}
+
+	post.Body = body
+	again, err := post.Expand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != body {
+		t.Errorf("the expanded body should be expanded again in the same way:\n%s", testutil.Diff(body, again))
+	}
+}
+
+func TestPostExpandWithProblems(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+	writePreprocessFiles(t)
+
+	post := model.Post{
+		Body: `{{undefined}} {{name}}
+<!-- include: src/missing.go -->
+<!-- include: src/main.go lines=5-100 -->
+<!-- include: src/main.go indent=2 -->`,
+	}
+	post.Path = "mine/post.md"
+	body, err := post.Expand()
+	e, ok := err.(model.ExpandError)
+	if !ok {
+		t.Fatalf("ExpandError should be returned: %v", err)
+	}
+	if len(e.Problems) != 4 ||
+		e.Problems[0] != "{{undefined}}: undefined variable" ||
+		!strings.HasPrefix(e.Problems[1], "<!-- include: src/missing.go -->: ") ||
+		e.Problems[2] != "<!-- include: src/main.go lines=5-100 -->: lines 5-100 are out of the file with 7 lines" ||
+		e.Problems[3] != "<!-- include: src/main.go indent=2 -->: unknown option indent" {
+		t.Errorf("wrong problems: %v", e.Problems)
+	}
+	if !strings.HasPrefix(body, "{{undefined}} qiitactl\n") {
+		t.Errorf("the other variables should be expanded: %s", body)
+	}
+}
+
+func TestCollapseIncludesKeepsEditedBlocks(t *testing.T) {
+	body := "<!-- include: src/main.go -->\n```go\ncode\n```\n\ntext\n<!-- /include -->"
+	if collapsed := model.CollapseIncludes(body); collapsed != body {
+		t.Errorf("the block without the end marker should be kept: %s", collapsed)
+	}
+}
+
+func TestPostExpandKeepsBlocksOfMissingFiles(t *testing.T) {
+	testutil.CleanUp()
+	defer testutil.CleanUp()
+
+	post := model.Post{
+		Body: "<!-- include: src/missing.go -->\n```go:missing.go\ncode\n```\n<!-- /include -->\n\ntext",
+	}
+	post.Path = "mine/post.md"
+	body, err := post.Expand()
+	if _, ok := err.(model.ExpandError); !ok {
+		t.Fatalf("ExpandError should be returned: %v", err)
+	}
+	if body != post.Body {
+		t.Errorf("the expanded block should be kept: %s", body)
+	}
+}
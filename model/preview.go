@@ -0,0 +1,78 @@
+package model
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/minodisk/qiitactl/api"
+)
+
+// PreviewsPath is the path of the file which records the private items made to preview drafts.
+var PreviewsPath = filepath.Join(DirWorkspace, "previews.json")
+
+// Previews records the IDs of the private items made to preview drafts with the paths of the drafts as keys.
+type Previews map[string]string
+
+// LoadPreviews loads the records from PreviewsPath.
+// Empty records are returned when the file doesn't exist.
+func LoadPreviews() (previews Previews, err error) {
+	previews = make(Previews)
+	b, err := ioutil.ReadFile(PreviewsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	err = json.Unmarshal(b, &previews)
+	return
+}
+
+// Save writes the records to PreviewsPath.
+func (previews Previews) Save() (err error) {
+	err = os.MkdirAll(filepath.Dir(PreviewsPath), 0755)
+	if err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(previews, "", "  ")
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(PreviewsPath, b, 0644)
+	return
+}
+
+// RenamePreview moves the record of the preview of the draft at from to the draft at to.
+func RenamePreview(from, to string) (err error) {
+	previews, err := LoadPreviews()
+	if err != nil {
+		return
+	}
+	id, ok := previews[from]
+	if !ok {
+		return
+	}
+	delete(previews, from)
+	previews[to] = id
+	err = previews.Save()
+	return
+}
+
+// Preview creates or updates the private item which shows the draft as it will be published.
+// id is the ID of the item made by the last preview, or empty to make a new item.
+// The draft itself isn't changed.
+func (post Post) Preview(client api.Client, id string) (preview Post, err error) {
+	preview = post
+	preview.ID = id
+	preview.Draft = false
+	preview.PublishAt = nil
+	preview.Private = true
+	if id == "" {
+		err = preview.Create(client, CreationOptions{})
+		return
+	}
+	err = preview.Update(client)
+	return
+}
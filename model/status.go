@@ -0,0 +1,99 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// State is the state of a local file of a post compared with the post in Qiita.
+type State string
+
+// States of local files of posts.
+const (
+	StateNew            State = "new"
+	StateModified       State = "modified"
+	StateRemoteModified State = "remote_modified"
+	StateBothModified   State = "both_modified"
+	StateRemoteDeleted  State = "remote_deleted"
+	StateDuplicate      State = "duplicate"
+)
+
+// Status is the state of a local file of a post.
+type Status struct {
+	Path  string `json:"path"`  // ファイルのパス
+	ID    string `json:"id"`    // 投稿の一意なID
+	State State  `json:"state"` // ファイルの状態
+}
+
+// Statuses compares the local files of posts with their snapshots and the posts in Qiita,
+// and returns the statuses of the files which differ, sorted by path.
+// The local changes are detected with the snapshots saved at the last fetch,
+// or with the remote posts when no snapshot is saved,
+// so that the local files which differ from the remote posts are never taken as unchanged.
+// The remote changes are detected with updated_at recorded in the files.
+func Statuses(local, remote Posts) (statuses []Status, err error) {
+	statuses = []Status{}
+	remotes := make(map[string]Post)
+	for _, post := range remote {
+		remotes[post.ID] = post
+	}
+	counts := make(map[string]int)
+	for _, post := range local {
+		counts[post.ID]++
+	}
+
+	for _, post := range local {
+		status := Status{
+			Path: post.Path,
+			ID:   post.ID,
+		}
+		switch {
+		case post.ID == "":
+			status.State = StateNew
+		case counts[post.ID] > 1:
+			status.State = StateDuplicate
+		default:
+			r, ok := remotes[post.ID]
+			if !ok {
+				status.State = StateRemoteDeleted
+				break
+			}
+			var base *Post
+			base, err = LoadBase(post.ID)
+			if err != nil {
+				return
+			}
+			remoteModified := !r.UpdatedAt.Equal(post.UpdatedAt.Time)
+			if base == nil {
+				base = &r
+			}
+			localModified := post.ContentHash() != base.ContentHash()
+			switch {
+			case localModified && remoteModified:
+				status.State = StateBothModified
+			case localModified:
+				status.State = StateModified
+			case remoteModified:
+				status.State = StateRemoteModified
+			default:
+				continue
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Path < statuses[j].Path
+	})
+	return
+}
+
+// ContentHash returns the hash of the title, the meta which can be updated and the body of the post.
+// The body is preprocessed as it is sent with SentBody,
+// so that a local file has the same hash as the post in Qiita which it is fetched from.
+func (post Post) ContentHash() string {
+	post.Body, _ = post.SentBody()
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\nslide: %t", post.DiffText(), post.Slide)))
+	return hex.EncodeToString(sum[:])
+}
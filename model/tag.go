@@ -20,3 +20,8 @@ func (tag Tag) MarshalJSON() ([]byte, error) {
 	}
 	return json.Marshal(t)
 }
+
+// String returns the name of the tag.
+func (tag Tag) String() string {
+	return tag.Name
+}
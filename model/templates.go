@@ -0,0 +1,181 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplatesDir is the directory of the templates of new posts.
+var TemplatesDir = filepath.Join(DirWorkspace, "templates")
+
+// Template is a template of new posts in TemplatesDir.
+type Template struct {
+	Name string // 拡張子を除いたファイル名
+	Path string // テンプレートのファイルのパス
+}
+
+// LoadTemplates returns the templates in TemplatesDir sorted by the names.
+func LoadTemplates() (templates []Template, err error) {
+	paths, err := filepath.Glob(filepath.Join(TemplatesDir, "*.md"))
+	if err != nil {
+		return
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		templates = append(templates, Template{
+			Name: strings.TrimSuffix(filepath.Base(path), ".md"),
+			Path: path,
+		})
+	}
+	return
+}
+
+// LoadTemplate returns the template with the name.
+func LoadTemplate(name string) (t Template, err error) {
+	t = Template{
+		Name: name,
+		Path: filepath.Join(TemplatesDir, name+".md"),
+	}
+	_, err = os.Stat(t.Path)
+	if os.IsNotExist(err) {
+		err = TemplateNotFoundError{Name: name}
+	}
+	return
+}
+
+// TemplateData is the data which templates are executed with.
+type TemplateData struct {
+	Title string    // 新しい投稿のタイトル
+	Team  string    // チームのID (Qiitaの投稿は空)
+	Now   time.Time // テンプレートを実行した時刻
+
+	user    func() (string, error)
+	prompt  func(name string) (string, error)
+	id      string
+	answers map[string]string
+}
+
+// Date returns the date when the template is executed like 2006-01-02.
+func (d *TemplateData) Date() string {
+	return d.Now.Format("2006-01-02")
+}
+
+// User returns the ID of the user who generates the post.
+func (d *TemplateData) User() (id string, err error) {
+	if d.id == "" {
+		d.id, err = d.user()
+	}
+	id = d.id
+	return
+}
+
+// Prompt asks the value of name and returns the answer.
+// The same name is asked only once.
+func (d *TemplateData) Prompt(name string) (answer string, err error) {
+	answer, ok := d.answers[name]
+	if ok {
+		return
+	}
+	answer, err = d.prompt(name)
+	if err != nil {
+		return
+	}
+	d.answers[name] = answer
+	return
+}
+
+// templateFuncs are the functions which can be called in templates.
+var templateFuncs = template.FuncMap{
+	"yaml": quoteYAML,
+}
+
+// quoteYAML returns v as a quoted scalar which can be written as a value in the meta.
+// The characters of HTML like > are escaped not to close the comment of the meta.
+func quoteYAML(v interface{}) (s string, err error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s = string(b)
+	return
+}
+
+// Execute returns a new post generated from the template with title and team.
+// The template is executed as a Go template with TemplateData,
+// which asks the ID of the user with user and the values prompted in the template with prompt,
+// and the result is read as a file of a post.
+// The values in the meta should be quoted with the yaml function.
+// title is used when the template has no title.
+func (t Template) Execute(title string, team *Team, user func() (string, error), prompt func(name string) (string, error)) (post Post, err error) {
+	b, err := ioutil.ReadFile(t.Path)
+	if err != nil {
+		return
+	}
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Funcs(templateFuncs).Parse(string(b))
+	if err != nil {
+		return
+	}
+	// The error of the user is returned as it is, not as the error of the template.
+	var userErr error
+	data := &TemplateData{
+		Title: title,
+		Now:   time.Now(),
+		user: func() (id string, err error) {
+			id, err = user()
+			if err != nil {
+				err = fmt.Errorf("template %s: fetch the authenticated user: %s", t.Name, err)
+				userErr = err
+			}
+			return
+		},
+		prompt:  prompt,
+		answers: make(map[string]string),
+	}
+	if team != nil {
+		data.Team = team.ID
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if userErr != nil {
+		err = userErr
+	}
+	if err != nil {
+		return
+	}
+	err = post.Decode(buf.Bytes())
+	if err != nil {
+		err = fmt.Errorf("%s: %s", t.Path, err)
+		return
+	}
+
+	now := Time{Time: data.Now}
+	post.ID = ""
+	post.URL = ""
+	post.CreatedAt = now
+	post.UpdatedAt = now
+	post.Team = team
+	post.Format = ""
+	post.Meta.raw = ""
+	if post.Title == "" {
+		post.Title = title
+	}
+	return
+}
+
+// TemplateNotFoundError occurs when the template with the name doesn't exist in TemplatesDir.
+type TemplateNotFoundError struct {
+	Name string
+}
+
+func (err TemplateNotFoundError) Error() (msg string) {
+	msg = fmt.Sprintf("template %s is not found in %s", err.Name, TemplatesDir)
+	return
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/minodisk/qiitactl/api"
+)
+
+// DirTrash is the directory where the files of deleted posts are moved.
+var DirTrash = filepath.Join(DirWorkspace, "trash")
+
+const (
+	trashFile     = "post.md"
+	trashMetaFile = "trash.json"
+)
+
+// Trash is a deleted post in the trash.
+type Trash struct {
+	ID        string          `json:"id"`         // 削除した投稿のID
+	Title     string          `json:"title"`      // 削除した投稿のタイトル
+	Path      string          `json:"path"`       // 削除した投稿のファイルがあったパス
+	DeletedAt Time            `json:"deleted_at"` // 削除した日時
+	Remote    json.RawMessage `json:"remote"`     // 削除する前のQiitaの投稿のJSON
+}
+
+func trashDir(id string) string {
+	return filepath.Join(DirTrash, id)
+}
+
+// MoveToTrash deletes the post in Qiita and moves the file of the post into the trash
+// with the JSON of the post in Qiita just before deleting it.
+func (post *Post) MoveToTrash(client api.Client) (err error) {
+	if post.Draft {
+		err = DraftError{
+			Path: post.Path,
+		}
+		return
+	}
+	if post.ID == "" {
+		err = EmptyIDError{}
+		return
+	}
+
+	subDomain := ""
+	if post.Team != nil {
+		subDomain = post.Team.ID
+	}
+	remote, _, err := client.Get(subDomain, fmt.Sprintf("/items/%s", post.ID), nil)
+	if err != nil {
+		return
+	}
+	t := Trash{
+		ID:        post.ID,
+		Title:     post.Title,
+		Path:      post.Path,
+		DeletedAt: Time{Time: time.Now()},
+		Remote:    json.RawMessage(remote),
+	}
+	dir := trashDir(post.ID)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, trashMetaFile), b, 0644)
+	if err != nil {
+		return
+	}
+
+	err = post.Delete(client)
+	if err != nil {
+		os.RemoveAll(dir)
+		return
+	}
+	err = os.Rename(post.Path, filepath.Join(dir, trashFile))
+	if err != nil {
+		return
+	}
+	err = os.Remove(basePath(post.ID))
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+// LoadTrashes loads the posts in the trash sorted by the time they were deleted.
+func LoadTrashes() (trashes []Trash, err error) {
+	infos, err := ioutil.ReadDir(DirTrash)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		var t Trash
+		t, err = LoadTrash(info.Name())
+		if err != nil {
+			return
+		}
+		trashes = append(trashes, t)
+	}
+	sort.Slice(trashes, func(i, j int) bool {
+		return trashes[i].DeletedAt.Before(trashes[j].DeletedAt.Time)
+	})
+	return
+}
+
+// LoadTrash loads the post with id in the trash.
+func LoadTrash(id string) (t Trash, err error) {
+	b, err := ioutil.ReadFile(filepath.Join(trashDir(id), trashMetaFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("%s isn't in the trash", id)
+		}
+		return
+	}
+	err = json.Unmarshal(b, &t)
+	return
+}
+
+// Post loads the file of the post in the trash to be created again as a new post.
+// The path of the post is the one where the file was, or a new one when another file exists there.
+func (t Trash) Post() (post Post, err error) {
+	post, err = NewPostWithFile(filepath.Join(trashDir(t.ID), trashFile))
+	if err != nil {
+		return
+	}
+	post.ID = ""
+	post.URL = ""
+	post.Path = uniquePath(t.Path)
+	return
+}
+
+// Purge removes the post from the trash permanently.
+func (t Trash) Purge() (err error) {
+	err = os.RemoveAll(trashDir(t.ID))
+	return
+}
@@ -1,5 +1,11 @@
 package model
 
+import (
+	"encoding/json"
+
+	"github.com/minodisk/qiitactl/api"
+)
+
 // User is data of user in Qiita.
 type User struct {
 	Description       string `json:"description"`         // 自己紹介文
@@ -18,3 +24,13 @@ type User struct {
 	TwitterScreenName string `json:"twitter_screen_name"` // Twitterのスクリーンネーム
 	WebsiteURL        string `json:"website_url"`         // 設定しているWebサイトのURL
 }
+
+// FetchAuthenticatedUser fetches the user authenticated with the access token.
+func FetchAuthenticatedUser(client api.Client) (user User, err error) {
+	body, _, err := client.Get("", "/authenticated_user", nil)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(body, &user)
+	return
+}
@@ -0,0 +1,96 @@
+// Package sandbox runs the code in posts in temporary directories.
+package sandbox
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Language is how the code in a language is run.
+type Language struct {
+	Filename string   // コードを書き込むファイル名
+	Command  []string // サンドボックスで実行するコマンド
+}
+
+// Languages are the languages which can be run, keyed by the language of code blocks.
+var Languages = map[string]Language{
+	"go":   {Filename: "main.go", Command: []string{"go", "run", "main.go"}},
+	"sh":   {Filename: "main.sh", Command: []string{"sh", "main.sh"}},
+	"bash": {Filename: "main.sh", Command: []string{"bash", "main.sh"}},
+}
+
+// Run writes code into a temporary directory and runs it with the command of lang in the directory.
+// The processes are killed when they don't finish within timeout.
+// output is what the processes write to stdout and stderr.
+func Run(lang, code string, timeout time.Duration) (output string, err error) {
+	l, ok := Languages[lang]
+	if !ok {
+		err = UnsupportedLanguageError{Lang: lang}
+		return
+	}
+	dir, err := ioutil.TempDir("", "qiitactl-exec-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, l.Filename), []byte(code+"\n"), 0644)
+	if err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	cmd := exec.Command(l.Command[0], l.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	setProcessGroup(cmd)
+	err = cmd.Start()
+	if err != nil {
+		return
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		killProcessGroup(cmd)
+		<-done
+		err = TimeoutError{Timeout: timeout}
+	}
+	output = buf.String()
+	return
+}
+
+// UnsupportedLanguageError occurs when code in a language which isn't in Languages is run.
+type UnsupportedLanguageError struct {
+	Lang string
+}
+
+func (err UnsupportedLanguageError) Error() (msg string) {
+	var langs []string
+	for lang := range Languages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	msg = fmt.Sprintf("%s can't be run: use one of %s", err.Lang, strings.Join(langs, ", "))
+	return
+}
+
+// TimeoutError occurs when code doesn't finish within the timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (err TimeoutError) Error() (msg string) {
+	msg = fmt.Sprintf("timed out after %s", err.Timeout)
+	return
+}
@@ -0,0 +1,49 @@
+package sandbox_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minodisk/qiitactl/sandbox"
+)
+
+func TestRun(t *testing.T) {
+	output, err := sandbox.Run("sh", "echo out\necho err >&2\nls", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "out\nerr\nmain.sh\n" {
+		t.Errorf("wrong output: %s", output)
+	}
+}
+
+func TestRunWithFailure(t *testing.T) {
+	output, err := sandbox.Run("sh", "echo failed\nexit 2", time.Minute)
+	if err == nil || err.Error() != "exit status 2" {
+		t.Errorf("the exit status should be returned: %v", err)
+	}
+	if output != "failed\n" {
+		t.Errorf("wrong output: %s", output)
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	start := time.Now()
+	output, err := sandbox.Run("sh", "echo start\nsleep 10 &\nwait", 200*time.Millisecond)
+	if _, ok := err.(sandbox.TimeoutError); !ok {
+		t.Fatalf("TimeoutError should be returned: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("the processes should be killed: %s", time.Since(start))
+	}
+	if output != "start\n" {
+		t.Errorf("wrong output: %s", output)
+	}
+}
+
+func TestRunWithUnsupportedLanguage(t *testing.T) {
+	_, err := sandbox.Run("cobol", "", time.Minute)
+	if err == nil || err.Error() != "cobol can't be run: use one of bash, go, sh" {
+		t.Errorf("wrong error: %v", err)
+	}
+}
@@ -46,6 +46,8 @@ func CleanUp() {
 	os.RemoveAll("mine")
 	os.RemoveAll("increments")
 	os.RemoveAll("foo")
+	os.RemoveAll("node_modules")
+	os.RemoveAll(".qiitactl")
 }
 
 func ResponseError(w http.ResponseWriter, statusCode int, err error) {
@@ -84,6 +86,9 @@ func findMarkdownFiles() (pathes []string, err error) {
 			return
 		}
 		if i.IsDir() {
+			if i.Name() == ".qiitactl" {
+				err = filepath.SkipDir
+			}
 			return
 		}
 		if filepath.Ext(p) != ".md" {